@@ -6,6 +6,7 @@ import "fmt"
 import "io"
 import "math/big"
 import "os"
+import "sort"
 
 // Contradiction is the type of error that is returned if an operation
 // results in a contradiction.
@@ -13,13 +14,16 @@ type Contradiction struct {
 	// Cell is the Cell that's the subject of the contradiction.
 	Cell *Cell
 	// Issue is the error message describing the contradiction.
-	Issue string
+	Issue      string
 	Constraint Constraint
-	Group *Group
+	Group      *Group
 }
 
 // Error implements the error interface.
 func (c *Contradiction) Error() string {
+	if c.Group == nil {
+		return fmt.Sprintf("Contradiction at [%d, %d]: %s", c.Cell.X, c.Cell.Y, c.Issue)
+	}
 	return fmt.Sprintf("Contradiction at [%d, %d], group %s: %s", c.Cell.X, c.Cell.Y, c.Group.label, c.Issue)
 }
 
@@ -61,6 +65,25 @@ type Puzzle struct {
 	// Justifications is a slice of all of the Justifications for what's
 	// been asserted about this Puzzle.
 	Justifications []*Justification
+	// Metadata describes the puzzle, for example its "title",
+	// "author", "source", "difficulty" or "variant".
+	Metadata map[string]string
+	// Solution, if not nil, is the known value of each Cell of the
+	// solved puzzle, for example as read from the puzzle's text.
+	Solution map[GridKey]int
+	// GuessSeed seeds the random numbers of GuessSolve's heuristics.
+	// If it's not 0 and there's no ValueOrderer the possible values of
+	// the Cell being guessed are tried in a random order, otherwise from
+	// smallest to largest.  Either way the same Puzzle is solved the
+	// same way every time.
+	GuessSeed int64
+	// CellChooser, if not nil, picks the Cell to guess rather than
+	// FewestCandidates.
+	CellChooser CellChooser
+	// ValueOrderer, if not nil, orders the values to guess.
+	ValueOrderer ValueOrderer
+	// SearchStats describe the search of the last GuessSolve.
+	SearchStats SearchStats
 }
 
 func (p *Puzzle) CheckIntegrity() []error {
@@ -95,6 +118,21 @@ func (p *Puzzle) Cell(x, y int) *Cell {
 	return c
 }
 
+// Cells returns the Cells of the Puzzle in row major order, which is
+// the order that the solver visits them in, unlike the random order of
+// ranging over the Grid.
+func (p *Puzzle) Cells() []*Cell {
+	cells := make([]*Cell, 0, len(p.Grid))
+	for y := 1; y <= p.Size; y++ {
+		for x := 1; x <= p.Size; x++ {
+			if c := p.Grid[MakeGridKey(x, y)]; c != nil {
+				cells = append(cells, c)
+			}
+		}
+	}
+	return cells
+}
+
 func (p *Puzzle) ShowJustifications() {
 	for _, j := range p.Justifications {
 		fmt.Fprintf(os.Stderr, "%s\n", j.Pretty())
@@ -127,8 +165,69 @@ func (p *Puzzle) MakeCells(size int) *Puzzle {
 	return p
 }
 
+// Clone returns a copy of the Puzzle that can be changed, for example
+// by solving it, without affecting the original.  The copy shares the
+// original's Constraints.
+func (p *Puzzle) Clone() *Puzzle {
+	clone := &Puzzle{
+		Size:         p.Size,
+		Grid:         make(map[GridKey]*Cell),
+		Progress:     p.Progress,
+		Universe:     p.Universe,
+		GuessSeed:    p.GuessSeed,
+		CellChooser:  p.CellChooser,
+		ValueOrderer: p.ValueOrderer,
+	}
+	for key, c := range p.Grid {
+		clone.Grid[key] = &Cell{
+			Puzzle:        clone,
+			X:             c.X,
+			Y:             c.Y,
+			Groups:        make([]*Group, 0),
+			Possibilities: c.Possibilities,
+		}
+	}
+	cell := func(c *Cell) *Cell {
+		return clone.Grid[MakeGridKey(c.X, c.Y)]
+	}
+	groups := make(map[*Group]*Group)
+	for _, g := range p.Groups {
+		cg := &Group{
+			puzzle:      clone,
+			label:       g.label,
+			constraints: append([]Constraint{}, g.constraints...),
+		}
+		for _, c := range g.cells {
+			cg.cells = append(cg.cells, cell(c))
+		}
+		groups[g] = cg
+		clone.AddGroup(cg)
+	}
+	for _, j := range p.Justifications {
+		cj := *j
+		cj.Cell = cell(j.Cell)
+		if j.Group != nil {
+			cj.Group = groups[j.Group]
+		}
+		clone.Justifications = append(clone.Justifications, &cj)
+	}
+	if p.Metadata != nil {
+		clone.Metadata = make(map[string]string)
+		for k, v := range p.Metadata {
+			clone.Metadata[k] = v
+		}
+	}
+	if p.Solution != nil {
+		clone.Solution = make(map[GridKey]int)
+		for k, v := range p.Solution {
+			clone.Solution[k] = v
+		}
+	}
+	return clone
+}
+
 func (p *Puzzle) IsSolved() bool {
-	for _, cell := range p.Grid {
+	for _, cell := range p.Cells() {
 		if solved, _ := cell.IsSolved(); !solved {
 			return false
 		}
@@ -182,6 +281,17 @@ func (c *Cell) HasPossibleValue(v int) bool {
 	return c.Possibilities.HasValue(v)
 }
 
+// IsGiven returns true and the value if the Cell's value was a given
+// of the puzzle rather than deduced.
+func (c *Cell) IsGiven() (bool, int) {
+	for _, j := range c.Puzzle.Justifications {
+		if j.Cell == c && j.Operation == MUST_BE && j.Constraint.Name() == Given.Name() {
+			return true, j.Value
+		}
+	}
+	return false, 0
+}
+
 type JustificationOp int
 
 const (
@@ -234,11 +344,11 @@ func (c *Cell) CantBe(v int, constraint Constraint, group *Group) (*Cell, error)
 	c.Possibilities = c.Possibilities.SetHasValue(v, false)
 	if c.Possibilities.Len() == 0 {
 		return c, &Contradiction{
-			Cell: c,
+			Cell:       c,
 			Constraint: constraint,
-			Group: group,
+			Group:      group,
 			Issue: fmt.Sprintf("No more possibilities after elimination of value %d from cell(%d, %d) by constraint %s",
-			v, c.X, c.Y, constraint.Name()),
+				v, c.X, c.Y, constraint.Name()),
 		}
 	}
 	if c.Possibilities != old {
@@ -260,10 +370,10 @@ func (c *Cell) MustBe(v int, constraint Constraint, group *Group) (*Cell, error)
 	old := c.Possibilities
 	if !c.HasPossibleValue(v) {
 		return c, &Contradiction{
-			Cell: c,
+			Cell:       c,
 			Constraint: constraint,
-			Group: group,
-			Issue: fmt.Sprintf("%d is not a possible Value for MustBe", v),
+			Group:      group,
+			Issue:      fmt.Sprintf("%d is not a possible Value for MustBe", v),
 		}
 	}
 	c.Possibilities = NewValueSet([]int{v})
@@ -327,7 +437,7 @@ type Group struct {
 }
 
 func NewGroup(p *Puzzle) *Group {
-	return &Group{ puzzle: p }
+	return &Group{puzzle: p}
 }
 
 func (g *Group) Puzzle() *Puzzle {
@@ -340,6 +450,16 @@ func (g *Group) Puzzle() *Puzzle {
 
 func (g *Group) Cells() []*Cell { return g.cells }
 
+// Label returns the label that identifies the Group in Justifications
+// and Contradictions.
+func (g *Group) Label() string { return g.label }
+
+// SetLabel sets the Group's label.
+func (g *Group) SetLabel(label string) *Group {
+	g.label = label
+	return g
+}
+
 func (g *Group) Constraints() []Constraint {
 	return g.constraints
 }
@@ -400,17 +520,27 @@ func init() {
 					count += 1
 				}
 			}
+			if count > c1.Possibilities.Len() {
+				// More cells than values, for example two cells
+				// with the same value.
+				return &Contradiction{
+					Cell:       c1,
+					Constraint: HereThenNotElsewhereConstraint,
+					Group:      g,
+					Issue:      fmt.Sprintf("%d cells can only be %s", count, c1.Possibilities.String(", ")),
+				}
+			}
 			if count == c1.Possibilities.Len() {
+				var err error
 				for _, c3 := range g.Cells() {
 					if c3.Possibilities != c1.Possibilities {
 						c1.Possibilities.DoValues(func(val int) bool {
-							_, err := c3.CantBe(val, HereThenNotElsewhereConstraint, g)
-							if err != nil {
-								g.puzzle.ShowJustifications()
-								panic(err)
-							}
-							return true
+							_, err = c3.CantBe(val, HereThenNotElsewhereConstraint, g)
+							return err == nil
 						})
+						if err != nil {
+							return err
+						}
 					}
 				}
 			}
@@ -430,14 +560,20 @@ func init() {
 		// cell that can have that value must have it.
 		// Is there a generalization of this for several values?
 		valueCells := make(map[int][]*Cell)
+		values := []int{}
 		for _, c := range g.Cells() {
 			c.Possibilities.DoValues(func(v int) bool {
+				if valueCells[v] == nil {
+					values = append(values, v)
+				}
 				valueCells[v] = append(valueCells[v], c)
 				return true
 			})
 		}
-		for v, cells := range valueCells {
-			if len(cells) == 1 {
+		// In order, so that the Justifications are the same every time.
+		sort.Ints(values)
+		for _, v := range values {
+			if cells := valueCells[v]; len(cells) == 1 {
 				if _, err := cells[0].MustBe(v, NotElsewhereThenHereConstraint, g); err != nil {
 					return err
 				}
@@ -454,7 +590,7 @@ func (p *Puzzle) AddLineGroups() *Puzzle {
 		g := &Group{
 			puzzle: p,
 			cells:  cells,
-			label: label,
+			label:  label,
 			constraints: []Constraint{
 				HereThenNotElsewhereConstraint,
 				NotElsewhereThenHereConstraint,
@@ -506,6 +642,30 @@ func (p *Puzzle) Add3x3Groups() *Puzzle {
 	return p
 }
 
+// AddDiagonalGroups adds the constraint that each value appears once
+// on each of the two long diagonals of the Puzzle, as in an X sudoku.
+func (p *Puzzle) AddDiagonalGroups() *Puzzle {
+	down := []*Cell{}
+	up := []*Cell{}
+	for i := 1; i <= p.Size; i++ {
+		down = append(down, p.Cell(i, i))
+		up = append(up, p.Cell(i, p.Size+1-i))
+	}
+	for i, cells := range [][]*Cell{down, up} {
+		g := &Group{
+			puzzle: p,
+			cells:  cells,
+			label:  fmt.Sprintf("diagonal%d", i+1),
+			constraints: []Constraint{
+				HereThenNotElsewhereConstraint,
+				NotElsewhereThenHereConstraint,
+			},
+		}
+		p.AddGroup(g)
+	}
+	return p
+}
+
 type KenKenOperator struct {
 	// Symbol is the name of the operator.
 	Symbol string
@@ -549,7 +709,7 @@ var KenKenOperators []KenKenOperator = []KenKenOperator{
 		Test: func(values []int, expect int) bool {
 			// Consider all possible conbinations of whether any given value
 			// is added or subtracted.
-			for sense := 1; sense < (1 << uint(len(values))) - 1; sense++ {
+			for sense := 1; sense < (1<<uint(len(values)))-1; sense++ {
 				accumulator := 0
 				for index, value := range values {
 					if sense&(1<<uint(index)) == 0 {
@@ -569,15 +729,15 @@ var KenKenOperators []KenKenOperator = []KenKenOperator{
 		Symbol: "Division",
 		Test: func(values []int, expect int) bool {
 			ratvalues := make([]*big.Rat, len(values), len(values))
-            for i := 0; i < len(values); i++ {
+			for i := 0; i < len(values); i++ {
 				ratvalues[i] = big.NewRat(int64(values[i]), 1)
 			}
 			ratexpect := big.NewRat(int64(expect), 1)
 			// Consider all possible conbinations of whether any given value
 			// is multiplied by or divided by.
-            // We needn't consider the all multiplication case nor the all
+			// We needn't consider the all multiplication case nor the all
 			// division case.
-			for sense := 1; sense < (1 << uint(len(values))) - 1; sense++ {
+			for sense := 1; sense < (1<<uint(len(values)))-1; sense++ {
 				result := big.NewRat(1, 1)
 				for index, value := range ratvalues {
 					if sense&(1<<uint(index)) == 0 {
@@ -649,12 +809,34 @@ func (c *KenKenCageConstraint) Name() string {
 	return c.name
 }
 
+// Operators returns the KenKenOperators of the constraint.
+func (c *KenKenCageConstraint) Operators() []*KenKenOperator {
+	return c.operators
+}
+
+// Expect returns the value that applying an operator to the values of
+// the cage's cells must produce.
+func (c *KenKenCageConstraint) Expect() int {
+	return c.expect
+}
+
 func (c *KenKenCageConstraint) DoConstraint(g *Group) error {
 	/*
 	   We might be able to do union and intersection of value sets when updating cells.
 	*/
 
 	cell_count := len(g.cells)
+	// A cell with no possibilities can't be counted through.
+	for _, cell := range g.cells {
+		if cell.Possibilities.IsEmpty() {
+			return &Contradiction{
+				Cell:       cell,
+				Constraint: c,
+				Group:      g,
+				Issue:      "no possible values",
+			}
+		}
+	}
 	// cell_value_indices contains an index into the ValueSet of each Cell of the Group.
 	// It counts through each possaible value of each cell.
 	cell_value_indices := make([]int, cell_count, cell_count)
@@ -690,6 +872,7 @@ func (c *KenKenCageConstraint) DoConstraint(g *Group) error {
 
 	// Eliminate each cell value possibility that does not appear in
 	// any of the acceptable combinations.
+	var err error
 	for cell_index := 0; cell_index < cell_count; cell_index++ {
 		cell := g.Cells()[cell_index]
 		cell.Possibilities.DoValues(func(p int) bool {
@@ -701,26 +884,28 @@ func (c *KenKenCageConstraint) DoConstraint(g *Group) error {
 				}
 			}
 			if !found {
-				_, err := cell.CantBe(p, c, g)
-				if err != nil {
-					g.puzzle.ShowJustifications()
-					panic(err)
-				}
+				_, err = cell.CantBe(p, c, g)
 			}
-			return true
+			return err == nil
 		})
+		if err != nil {
+			return err
+		}
 	}
 	return nil
 }
 
 func MakeKenKenConstraint(operators []*KenKenOperator, expect int) Constraint {
-	return &KenKenCageConstraint{
+	c := &KenKenCageConstraint{
 		operators: operators,
 		expect:    expect,
 	}
+	// Clones of a Puzzle share its Constraints, so the name is made
+	// now rather than by goroutines searching the clones at once.
+	c.name = c.makeName()
+	return c
 }
 
-
 func NewEmptySudoku() *Puzzle {
 	p := &Puzzle{}
 	p.MakeCells(9)
@@ -728,4 +913,3 @@ func NewEmptySudoku() *Puzzle {
 	p.Add3x3Groups()
 	return p
 }
-
@@ -164,23 +164,19 @@ func TestSudoku2(t *testing.T) {
 	given(5, 9, 3)
 	given(8, 9, 4)
 
-	show := func() {
-		var b bytes.Buffer
-		p.Show(&b)
-		t.Log(b.String())
-	}
-
-	show()
-
-	if err := p.DoConstraints(); err != nil {
-		t.Errorf("Error during DoConstraints: %s", err.Error())
-	}
-	show()
-	for _, j := range p.Justifications {
-		t.Log(j.Pretty())
-	}
-	if !p.IsSolved() {
-		t.Errorf("Not solved")
+	// Row 9 has two 9s, given(1, 9, 9) and given(2, 9, 9), as printed
+	// in the newspaper, so the puzzle has no solution.  This test used
+	// to expect one: the constraints ignored the two 9s and filled in
+	// a grid that also had two 7s in row 9 and two 6s in row 6, which
+	// IsSolved accepted because each cell had one value.
+	err := p.DoConstraints()
+	c, ok := err.(*Contradiction)
+	if !ok {
+		t.Fatalf("Want a Contradiction for the two 9s in row 9, got %v", err)
+	}
+	if c.Constraint.Name() != "HereThenNotElsewhereConstraint" || c.Cell.Y != 9 ||
+		!c.Cell.HasPossibleValue(9) || c.Group.Label() != "row9" {
+		t.Errorf("Want a Contradiction for the two 9s in row 9, got %s", c)
 	}
 }
 
@@ -312,3 +308,43 @@ func TestKenKenDivisioinConstraint(t *testing.T) {
 		t.Errorf("KenKen cage constraint failed.")
 	}
 }
+
+func TestKenKenEmptyCell(t *testing.T) {
+	p := &Puzzle{}
+	p.MakeCells(6)
+	p.AddLineGroups()
+	g := NewGroup(p)
+	g.AddCell(p.Cell(1, 1))
+	g.AddCell(p.Cell(1, 2))
+	g.AddConstraint(MakeKenKenConstraint([]*KenKenOperator{GetKenKenOperator("Addition")}, 7))
+	p.Cell(1, 2).Possibilities = ValueSet(0)
+	if _, ok := g.DoConstraints().(*Contradiction); !ok {
+		t.Errorf("A cage with an empty cell should be a Contradiction")
+	}
+}
+
+func TestClone(t *testing.T) {
+	p := NewEmptySudoku()
+	p.Cell(1, 1).MustBe(1, Given, nil)
+	p.SetMetadata("title", "clone")
+	clone := p.Clone()
+	if errors := clone.CheckIntegrity(); len(errors) > 0 {
+		t.Fatalf("%v", errors)
+	}
+	if given, v := clone.Cell(1, 1).IsGiven(); !given || v != 1 {
+		t.Errorf("The given wasn't cloned")
+	}
+	if len(clone.Groups) != len(p.Groups) || len(clone.Cell(5, 5).Groups) != 3 {
+		t.Errorf("The groups weren't cloned")
+	}
+	if err := clone.DoConstraints(); err != nil {
+		t.Fatalf("%s", err)
+	}
+	clone.SetMetadata("title", "changed")
+	if p.Cell(2, 1).HasPossibleValue(1) == false || p.Metadata["title"] != "clone" {
+		t.Errorf("Changing the clone changed the original")
+	}
+	if clone.Cell(2, 1).HasPossibleValue(1) {
+		t.Errorf("The clone's constraints weren't applied")
+	}
+}
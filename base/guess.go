@@ -2,45 +2,135 @@
 // guessing.
 package base
 
+import "math/rand"
+
 var Pick = FunctionConstraint{
 	name:       "Pick",
 	constraint: (func(g *Group) error { return nil }),
 }
 
-// Find the first Cell that isn't solved and pick a value for it.
+// Pick a value for an unsolved Cell, using the Puzzle's CellChooser and
+// ValueOrderer: by default the smallest possible value of the first
+// Cell, in row major order, with the fewest.
 func (p *Puzzle) GuessOnce() error {
-	if p.IsSolved() {
+	unsolved := p.unsolvedCells()
+	if len(unsolved) == 0 {
 		return nil
 	}
-	var guess_err error = nil
-	for _, cell := range p.Grid {
-		if s, _ := cell.IsSolved(); !s {
-			cell.Possibilities.DoValues(
-				func(value int) bool {
-					_, guess_err = cell.MustBe(value, Pick, nil)
-					return false
-				})
-			if guess_err != nil {
-				return guess_err
+	rng := rand.New(rand.NewSource(p.GuessSeed))
+	cell := p.cellChooser().ChooseCell(p, unsolved, rng)
+	values := p.valueOrderer().OrderValues(cell, cell.Possibilities.Values(), rng)
+	_, err := cell.MustBe(values[0], Pick, nil)
+	return err
+}
+
+// guessCell returns the unsolved Cell with the fewest possible values,
+// the first in row major order if there's a tie, or nil if the puzzle
+// is solved.
+func (p *Puzzle) guessCell() *Cell {
+	var best *Cell
+	for y := 1; y <= p.Size; y++ {
+		for x := 1; x <= p.Size; x++ {
+			c := p.Cell(x, y)
+			if solved, _ := c.IsSolved(); solved {
+				continue
+			}
+			if best == nil || c.Possibilities.Len() < best.Possibilities.Len() {
+				best = c
 			}
-			break
 		}
 	}
-	return nil
+	return best
+}
+
+// puzzleState records what a guess can change so that it can be undone.
+type puzzleState struct {
+	possibilities  map[*Cell]ValueSet
+	justifications int
+}
+
+func (p *Puzzle) saveState() *puzzleState {
+	s := &puzzleState{
+		possibilities:  make(map[*Cell]ValueSet),
+		justifications: len(p.Justifications),
+	}
+	for _, c := range p.Cells() {
+		s.possibilities[c] = c.Possibilities
+	}
+	return s
 }
 
-// Try to solve the puzzle by guessing.
+func (p *Puzzle) restoreState(s *puzzleState) {
+	for c, vs := range s.possibilities {
+		c.Possibilities = vs
+	}
+	p.Justifications = p.Justifications[:s.justifications]
+}
+
+// Try to solve the puzzle by guessing.  When constraint propagation
+// can't make any more progress a value is picked for an unsolved Cell,
+// chosen by the Puzzle's CellChooser, and its possible values are
+// tried in the order of its ValueOrderer.  If a value leads to a
+// Contradiction the guess is undone and the next value is tried.  The
+// SearchStats record how much searching that took.
 func (p *Puzzle) GuessSolve() error {
-	for {
-		if err := p.DoConstraints(); err != nil {
-			return err
-		}
-		if p.IsSolved() {
-			break
-		}
-		if err := p.GuessOnce(); err != nil {
-			return err
+	p.SearchStats = SearchStats{}
+	rng := rand.New(rand.NewSource(p.GuessSeed))
+	return p.guessSolve(rng, p.cellChooser(), p.valueOrderer(), 0)
+}
+
+func (p *Puzzle) guessSolve(rng *rand.Rand, chooser CellChooser, orderer ValueOrderer, depth int) error {
+	if err := p.DoConstraints(); err != nil {
+		return err
+	}
+	unsolved := p.unsolvedCells()
+	if len(unsolved) == 0 {
+		return nil
+	}
+	if depth+1 > p.SearchStats.MaxDepth {
+		p.SearchStats.MaxDepth = depth + 1
+	}
+	cell := chooser.ChooseCell(p, unsolved, rng)
+	var err error
+	for _, value := range orderer.OrderValues(cell, cell.Possibilities.Values(), rng) {
+		state := p.saveState()
+		p.SearchStats.Guesses += 1
+		if _, err = cell.MustBe(value, Pick, nil); err == nil {
+			if err = p.guessSolve(rng, chooser, orderer, depth+1); err == nil {
+				return nil
+			}
 		}
+		p.SearchStats.Backtracks += 1
+		p.restoreState(state)
+	}
+	return err
+}
+
+// CountSolutions returns the number of solutions of the puzzle, counting
+// no further than limit unless limit is 0.  A limit of 2 is enough to
+// tell whether the solution is unique.  The Puzzle is left as it was.
+func (p *Puzzle) CountSolutions(limit int) int {
+	state := p.saveState()
+	defer p.restoreState(state)
+	if err := p.DoConstraints(); err != nil {
+		return 0
 	}
-	return nil
+	cell := p.guessCell()
+	if cell == nil {
+		return 1
+	}
+	count := 0
+	cell.Possibilities.DoValues(func(value int) bool {
+		guess := p.saveState()
+		if _, err := cell.MustBe(value, Pick, nil); err == nil {
+			remaining := 0
+			if limit > 0 {
+				remaining = limit - count
+			}
+			count += p.CountSolutions(remaining)
+		}
+		p.restoreState(guess)
+		return limit == 0 || count < limit
+	})
+	return count
 }
@@ -0,0 +1,164 @@
+package base
+
+import "fmt"
+import "strings"
+import "testing"
+
+// sudokuFromLine returns a sudoku with the givens of an 81 character
+// line where a '.' is an empty cell.
+func sudokuFromLine(t *testing.T, line string) *Puzzle {
+	p := NewEmptySudoku()
+	for i, ch := range line {
+		if ch == '.' {
+			continue
+		}
+		if _, err := p.Cell(i%9+1, i/9+1).MustBe(int(ch-'0'), Given, nil); err != nil {
+			t.Fatalf("%s", err)
+		}
+	}
+	return p
+}
+
+// A sudoku that needs guessing.
+const hardSudoku = "8..........36......7..9.2...5...7.......457.....1...3...1....68..85...1..9....4.."
+
+func TestGuessSolve(t *testing.T) {
+	p := sudokuFromLine(t, hardSudoku)
+	if err := p.GuessSolve(); err != nil {
+		t.Fatalf("Error while guessing %s", err.Error())
+	}
+	if !p.IsSolved() {
+		t.Fatalf("Not solved")
+	}
+	for x, ch := range "812753649" {
+		if want, got := int(ch-'0'), p.Cell(x+1, 1).Possibilities.MustGet(0); got != want {
+			t.Errorf("Cell(%d, 1): want %d, got %d", x+1, want, got)
+		}
+	}
+	for _, g := range p.Groups {
+		seen := ValueSet(0)
+		for _, c := range g.Cells() {
+			seen = seen.Union(c.Possibilities)
+		}
+		if seen != p.Universe {
+			t.Errorf("Group %s doesn't have every value", g.Label())
+		}
+	}
+}
+
+func TestDuplicateContradiction(t *testing.T) {
+	p := NewEmptySudoku()
+	p.Cell(1, 1).MustBe(5, Given, nil)
+	p.Cell(9, 1).MustBe(5, Given, nil)
+	if err := p.DoConstraints(); err == nil {
+		t.Errorf("Two 5s in row 1 should be a contradiction")
+	} else if _, ok := err.(*Contradiction); !ok {
+		t.Errorf("Expected a Contradiction, not %#v", err)
+	}
+}
+
+func TestCountSolutions(t *testing.T) {
+	p := sudokuFromLine(t, hardSudoku)
+	before := p.ValueCount()
+	if got := p.CountSolutions(0); got != 1 {
+		t.Errorf("Expected a unique solution, got %d", got)
+	}
+	if got := p.ValueCount(); got != before {
+		t.Errorf("CountSolutions changed the puzzle")
+	}
+	// Without its first given the puzzle has more than one solution.
+	p = sudokuFromLine(t, "."+hardSudoku[1:])
+	if got := p.CountSolutions(2); got != 2 {
+		t.Errorf("Expected to count to the limit of 2, got %d", got)
+	}
+	p.Cell(1, 1).MustBe(1, Given, nil)
+	p.Cell(2, 1).MustBe(1, Given, nil)
+	if got := p.CountSolutions(0); got != 0 {
+		t.Errorf("Expected no solutions, got %d", got)
+	}
+}
+
+func TestHint(t *testing.T) {
+	p := NewEmptySudoku()
+	for x := 1; x <= 8; x++ {
+		p.Cell(x, 1).MustBe(x, Given, nil)
+	}
+	cell, justifications, err := p.Hint()
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	if cell == nil || cell.X != 9 || cell.Y != 1 {
+		t.Fatalf("Expected the hint to be about Cell(9, 1): %v", cell)
+	}
+	last := justifications[len(justifications)-1]
+	if last.Cell != cell || cell.Possibilities.MustGet(0) != 9 {
+		t.Errorf("Expected Cell(9, 1) to be 9: %s", last.Pretty())
+	}
+	// Nothing can be deduced about an empty sudoku.
+	cell, justifications, err = NewEmptySudoku().Hint()
+	if err != nil || cell != nil || len(justifications) != 0 {
+		t.Errorf("Expected no hint: %v %v %v", cell, justifications, err)
+	}
+}
+
+func TestGuessOnce(t *testing.T) {
+	p := sudokuFromLine(t, hardSudoku)
+	if err := p.GuessOnce(); err != nil {
+		t.Fatalf("%s", err)
+	}
+	j := p.Justifications[len(p.Justifications)-1]
+	if j.Cell.X != 2 || j.Cell.Y != 1 || j.Value != 1 || j.Constraint.Name() != Pick.Name() {
+		t.Errorf("Expected a guess of 1 at (2, 1), got %s", j.Pretty())
+	}
+}
+
+func TestGuessSeed(t *testing.T) {
+	solve := func(seed int64) (string, []string) {
+		p := NewEmptySudoku()
+		p.GuessSeed = seed
+		if err := p.GuessSolve(); err != nil {
+			t.Fatalf("%s", err)
+		}
+		row := ""
+		for x := 1; x <= 9; x++ {
+			_, v := p.Cell(x, 1).IsSolved()
+			row += fmt.Sprintf("%d", v)
+		}
+		log := []string{}
+		for _, j := range p.Justifications {
+			log = append(log, j.Pretty())
+		}
+		return row, log
+	}
+	row, _ := solve(0)
+	if row != "123456789" {
+		t.Errorf("Expected the smallest values first, got %s", row)
+	}
+	row1, log1 := solve(42)
+	row2, log2 := solve(42)
+	if row1 == row || row1 != row2 {
+		t.Errorf("Expected a different but reproducible solution, got %s and %s", row1, row2)
+	}
+	if strings.Join(log1, "\n") != strings.Join(log2, "\n") {
+		t.Errorf("The Justifications differ between runs with the same seed")
+	}
+}
+
+func TestRepeatableJustifications(t *testing.T) {
+	var first string
+	for i := 0; i < 5; i++ {
+		p := sudokuFromLine(t, hardSudoku)
+		if err := p.GuessSolve(); err != nil {
+			t.Fatalf("%s", err)
+		}
+		log := []string{}
+		for _, j := range p.Justifications {
+			log = append(log, j.Pretty())
+		}
+		if i == 0 {
+			first = strings.Join(log, "\n")
+		} else if strings.Join(log, "\n") != first {
+			t.Fatalf("The Justifications differ between solutions of the same puzzle")
+		}
+	}
+}
@@ -0,0 +1,219 @@
+// Heuristics that guide the search when guessing.
+package base
+
+import "math/rand"
+import "sort"
+
+// A CellChooser picks the Cell whose value is guessed next.
+type CellChooser interface {
+	Name() string
+	// ChooseCell returns one of the unsolved Cells, which are in row
+	// major order.  rng is seeded by the Puzzle's GuessSeed.
+	ChooseCell(p *Puzzle, unsolved []*Cell, rng *rand.Rand) *Cell
+}
+
+// A ValueOrderer orders the possible values of the Cell being guessed.
+type ValueOrderer interface {
+	Name() string
+	// OrderValues returns the values, which are the Cell's possible
+	// values from smallest to largest, in the order to guess them in.
+	// rng is seeded by the Puzzle's GuessSeed.
+	OrderValues(c *Cell, values []int, rng *rand.Rand) []int
+}
+
+type FunctionCellChooser struct {
+	name   string
+	choose func(*Puzzle, []*Cell, *rand.Rand) *Cell
+}
+
+func (chooser FunctionCellChooser) Name() string {
+	return chooser.name
+}
+
+func (chooser FunctionCellChooser) ChooseCell(p *Puzzle, unsolved []*Cell, rng *rand.Rand) *Cell {
+	return chooser.choose(p, unsolved, rng)
+}
+
+type FunctionValueOrderer struct {
+	name  string
+	order func(*Cell, []int, *rand.Rand) []int
+}
+
+func (orderer FunctionValueOrderer) Name() string {
+	return orderer.name
+}
+
+func (orderer FunctionValueOrderer) OrderValues(c *Cell, values []int, rng *rand.Rand) []int {
+	return orderer.order(c, values, rng)
+}
+
+// FewestCandidates chooses the Cell with the fewest possible values,
+// the first in row major order if there's a tie.  Guessing where there
+// are fewest choices keeps the search small, which matters most for
+// large KenKens.
+var FewestCandidates = FunctionCellChooser{
+	name: "fewest",
+	choose: func(p *Puzzle, unsolved []*Cell, rng *rand.Rand) *Cell {
+		var best *Cell
+		for _, c := range unsolved {
+			if best == nil || c.Possibilities.Len() < best.Possibilities.Len() {
+				best = c
+			}
+		}
+		return best
+	},
+}
+
+// MostConstrained chooses the Cell whose Groups have the most solved
+// Cells, breaking ties by the fewest possible values and then row major
+// order.
+var MostConstrained = FunctionCellChooser{
+	name: "constrained",
+	choose: func(p *Puzzle, unsolved []*Cell, rng *rand.Rand) *Cell {
+		var best *Cell
+		best_solved := 0
+		for _, c := range unsolved {
+			solved := 0
+			for _, g := range c.Groups {
+				for _, other := range g.Cells() {
+					if s, _ := other.IsSolved(); s {
+						solved += 1
+					}
+				}
+			}
+			if best == nil || solved > best_solved ||
+				(solved == best_solved && c.Possibilities.Len() < best.Possibilities.Len()) {
+				best, best_solved = c, solved
+			}
+		}
+		return best
+	},
+}
+
+// RandomCell chooses any of the unsolved Cells at random.
+var RandomCell = FunctionCellChooser{
+	name: "random",
+	choose: func(p *Puzzle, unsolved []*Cell, rng *rand.Rand) *Cell {
+		return unsolved[rng.Intn(len(unsolved))]
+	},
+}
+
+// SmallestValue orders the values from smallest to largest.
+var SmallestValue = FunctionValueOrderer{
+	name: "smallest",
+	order: func(c *Cell, values []int, rng *rand.Rand) []int {
+		return values
+	},
+}
+
+// LeastConstraining orders the values by how many other unsolved Cells
+// of the Cell's Groups could have them, fewest first, so that the
+// guess that rules out the fewest possibilities elsewhere is tried
+// first.
+var LeastConstraining = FunctionValueOrderer{
+	name: "least-constraining",
+	order: func(c *Cell, values []int, rng *rand.Rand) []int {
+		ruled_out := make(map[int]int)
+		for _, g := range c.Groups {
+			for _, other := range g.Cells() {
+				if other == c {
+					continue
+				}
+				if s, _ := other.IsSolved(); s {
+					continue
+				}
+				for _, v := range values {
+					if other.HasPossibleValue(v) {
+						ruled_out[v] += 1
+					}
+				}
+			}
+		}
+		sort.SliceStable(values, func(i, j int) bool {
+			return ruled_out[values[i]] < ruled_out[values[j]]
+		})
+		return values
+	},
+}
+
+// RandomValues orders the values at random.
+var RandomValues = FunctionValueOrderer{
+	name: "random",
+	order: func(c *Cell, values []int, rng *rand.Rand) []int {
+		rng.Shuffle(len(values), func(i, j int) {
+			values[i], values[j] = values[j], values[i]
+		})
+		return values
+	},
+}
+
+// CellChoosers are the CellChoosers that can be named.
+var CellChoosers = []CellChooser{FewestCandidates, MostConstrained, RandomCell}
+
+// ValueOrderers are the ValueOrderers that can be named.
+var ValueOrderers = []ValueOrderer{SmallestValue, LeastConstraining, RandomValues}
+
+// GetCellChooser returns the CellChooser with the specified name, or
+// nil if none is found.
+func GetCellChooser(name string) CellChooser {
+	for _, c := range CellChoosers {
+		if c.Name() == name {
+			return c
+		}
+	}
+	return nil
+}
+
+// GetValueOrderer returns the ValueOrderer with the specified name, or
+// nil if none is found.
+func GetValueOrderer(name string) ValueOrderer {
+	for _, o := range ValueOrderers {
+		if o.Name() == name {
+			return o
+		}
+	}
+	return nil
+}
+
+// SearchStats describe the search of the last GuessSolve or
+// SolveContext, so that heuristics can be compared.
+type SearchStats struct {
+	// Guesses is the number of values that were guessed.
+	Guesses int
+	// Backtracks is the number of guesses that led to a Contradiction
+	// and were undone.
+	Backtracks int
+	// MaxDepth is the most guesses that were in effect at once.
+	MaxDepth int
+}
+
+// cellChooser returns the Puzzle's CellChooser or the default.
+func (p *Puzzle) cellChooser() CellChooser {
+	if p.CellChooser != nil {
+		return p.CellChooser
+	}
+	return FewestCandidates
+}
+
+// valueOrderer returns the Puzzle's ValueOrderer or the default, which
+// is RandomValues if there's a GuessSeed and SmallestValue otherwise.
+func (p *Puzzle) valueOrderer() ValueOrderer {
+	if p.ValueOrderer != nil {
+		return p.ValueOrderer
+	}
+	if p.GuessSeed != 0 {
+		return RandomValues
+	}
+	return SmallestValue
+}
+
+// unsolvedCells returns the unsolved Cells in row major order.
+func (p *Puzzle) unsolvedCells() []*Cell {
+	unsolved := []*Cell{}
+	for _, c := range p.Cells() {
+		if solved, _ := c.IsSolved(); !solved {
+			unsolved = append(unsolved, c)
+		}
+	}
+	return unsolved
+}
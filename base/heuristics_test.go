@@ -0,0 +1,58 @@
+package base
+
+import "testing"
+
+func TestHeuristics(t *testing.T) {
+	expect := sudokuFromLine(t, hardSudoku)
+	if err := expect.GuessSolve(); err != nil {
+		t.Fatalf("%s", err)
+	}
+	for _, chooser := range CellChoosers {
+		for _, orderer := range ValueOrderers {
+			name := chooser.Name() + " " + orderer.Name()
+			p := sudokuFromLine(t, hardSudoku)
+			p.CellChooser = chooser
+			p.ValueOrderer = orderer
+			p.GuessSeed = 3
+			if err := p.GuessSolve(); err != nil {
+				t.Fatalf("%s: %s", name, err)
+			}
+			for _, c := range p.Cells() {
+				_, v := c.IsSolved()
+				if _, e := expect.Cell(c.X, c.Y).IsSolved(); v != e {
+					t.Fatalf("%s: expected %d at (%d, %d), got %d", name, e, c.X, c.Y, v)
+				}
+			}
+			stats := p.SearchStats
+			t.Logf("%s: %+v", name, stats)
+			if stats.Guesses == 0 || stats.MaxDepth == 0 || stats.Backtracks >= stats.Guesses {
+				t.Errorf("%s: unlikely SearchStats %+v", name, stats)
+			}
+			again := sudokuFromLine(t, hardSudoku)
+			again.CellChooser = chooser
+			again.ValueOrderer = orderer
+			again.GuessSeed = 3
+			again.GuessSolve()
+			if again.SearchStats != stats {
+				t.Errorf("%s: the same seed searched differently: %+v, %+v", name, stats, again.SearchStats)
+			}
+		}
+	}
+	if c := GetCellChooser("fewest"); c == nil || c.Name() != "fewest" || GetValueOrderer("nonesuch") != nil {
+		t.Errorf("GetCellChooser or GetValueOrderer failed")
+	}
+}
+
+func TestLeastConstraining(t *testing.T) {
+	p := NewEmptySudoku()
+	for x := 2; x <= 9; x++ {
+		if _, err := p.Cell(x, 1).CantBe(5, Given, nil); err != nil {
+			t.Fatalf("%s", err)
+		}
+	}
+	c := p.Cell(1, 1)
+	values := LeastConstraining.OrderValues(c, c.Possibilities.Values(), nil)
+	if values[0] != 5 || values[1] != 1 || values[8] != 9 {
+		t.Errorf("Expected 5 and then the rest in order, got %v", values)
+	}
+}
@@ -0,0 +1,58 @@
+// Find the next deduction that can be made about a Puzzle.
+package base
+
+// Hint applies the Constraints of one Group after another until a Cell
+// that wasn't solved is solved.  It returns that Cell and the
+// Justifications of what was deduced about it, the last of which solved
+// it.  If constraint propagation stops making progress before a Cell
+// is solved the Cell is nil: a guess is needed.  The deductions are
+// made to the Puzzle itself so Hint can be called repeatedly to step
+// through a solution.
+func (p *Puzzle) Hint() (*Cell, []*Justification, error) {
+	solved := func() map[*Cell]bool {
+		s := make(map[*Cell]bool)
+		for _, c := range p.Cells() {
+			if ok, _ := c.IsSolved(); ok {
+				s[c] = true
+			}
+		}
+		return s
+	}
+	before := solved()
+	start := len(p.Justifications)
+	for {
+		tick := p.Progress
+		for _, g := range p.Groups {
+			if err := g.DoConstraints(); err != nil {
+				return nil, nil, err
+			}
+			if p.Progress == tick {
+				continue
+			}
+			after := solved()
+			for y := 1; y <= p.Size; y++ {
+				for x := 1; x <= p.Size; x++ {
+					c := p.Cell(x, y)
+					if after[c] && !before[c] {
+						return c, p.cellJustifications(c, start), nil
+					}
+				}
+			}
+		}
+		if p.Progress == tick {
+			return nil, nil, nil
+		}
+	}
+}
+
+// cellJustifications returns the Justifications about the Cell that
+// were made since the start'th.
+func (p *Puzzle) cellJustifications(c *Cell, start int) []*Justification {
+	found := []*Justification{}
+	for _, j := range p.Justifications[start:] {
+		if j.Cell == c {
+			found = append(found, j)
+		}
+	}
+	return found
+}
@@ -0,0 +1,41 @@
+// Descriptive information about a puzzle that doesn't affect its
+// solution.
+package base
+
+import "sort"
+
+// MetadataOrder lists the usual Metadata keys in the order that they
+// are shown.
+var MetadataOrder = []string{"title", "author", "source", "difficulty", "variant"}
+
+// MetadataKeys returns the keys of the Puzzle's Metadata in the order
+// that they should be shown: those in MetadataOrder first and then the
+// rest alphabetically.
+func (p *Puzzle) MetadataKeys() []string {
+	keys := []string{}
+	usual := make(map[string]bool)
+	for _, key := range MetadataOrder {
+		usual[key] = true
+		if _, ok := p.Metadata[key]; ok {
+			keys = append(keys, key)
+		}
+	}
+	others := []string{}
+	for key := range p.Metadata {
+		if !usual[key] {
+			others = append(others, key)
+		}
+	}
+	sort.Strings(others)
+	return append(keys, others...)
+}
+
+// SetMetadata sets the value of a Metadata key, making the Metadata map
+// if necessary.
+func (p *Puzzle) SetMetadata(key string, value string) *Puzzle {
+	if p.Metadata == nil {
+		p.Metadata = make(map[string]string)
+	}
+	p.Metadata[key] = value
+	return p
+}
@@ -0,0 +1,18 @@
+package base
+
+import "strings"
+import "testing"
+
+func TestMetadataKeys(t *testing.T) {
+	p := NewEmptySudoku()
+	if keys := p.MetadataKeys(); len(keys) != 0 {
+		t.Errorf("Expected no keys, got %v", keys)
+	}
+	p.SetMetadata("zebra", "stripes")
+	p.SetMetadata("author", "Shye")
+	p.SetMetadata("apple", "red")
+	p.SetMetadata("title", "Binary Fusion")
+	if want, got := "title,author,apple,zebra", strings.Join(p.MetadataKeys(), ","); got != want {
+		t.Errorf("want %s, got %s", want, got)
+	}
+}
@@ -0,0 +1,197 @@
+// Searching for solutions with several goroutines at once.
+package base
+
+import "context"
+import "fmt"
+import "math/rand"
+import "runtime"
+import "sync"
+
+// SolveOptions control how SolveContext searches.
+type SolveOptions struct {
+	// Workers is the number of goroutines that search at once, the
+	// number of CPUs if 0.
+	Workers int
+	// All finds every solution rather than the first one found.
+	All bool
+	// Limit, if not 0, is the most solutions that All finds.
+	Limit int
+}
+
+// subtree is a part of the search tree: a clone of the puzzle with
+// the guesses that lead to it made.
+type subtree struct {
+	puzzle *Puzzle
+	depth  int
+}
+
+// SolveContext solves the puzzle like GuessSolve, but splits the search
+// among several goroutines, each with a clone of the puzzle.  The
+// guesses at the top of the search tree are made first, until there
+// are enough unsolved clones to keep the goroutines busy, and then each
+// clone is searched by one of them.  It returns solved clones, each
+// with the Justifications that led to it: the first one found, or,
+// with All, every solution.  The search stops when ctx is cancelled or
+// its deadline passes, and the solutions found by then are returned
+// with ctx's error.  The Puzzle is left as it was, except for its
+// SearchStats, which add up the searches of all of the goroutines.
+func (p *Puzzle) SolveContext(ctx context.Context, options SolveOptions) ([]*Puzzle, error) {
+	workers := options.Workers
+	if workers < 1 {
+		workers = runtime.NumCPU()
+	}
+	limit := 1
+	if options.All {
+		limit = options.Limit
+	}
+	chooser := p.cellChooser()
+	orderer := p.valueOrderer()
+	p.SearchStats = SearchStats{}
+
+	search, cancel := context.WithCancel(ctx)
+	defer cancel()
+	var lock sync.Mutex
+	solutions := []*Puzzle{}
+	// found records a solution and returns false once there are enough.
+	found := func(solution *Puzzle) bool {
+		lock.Lock()
+		defer lock.Unlock()
+		if limit > 0 && len(solutions) >= limit {
+			return false
+		}
+		solutions = append(solutions, solution)
+		if limit > 0 && len(solutions) >= limit {
+			cancel()
+			return false
+		}
+		return true
+	}
+	stats := func(s SearchStats) {
+		lock.Lock()
+		defer lock.Unlock()
+		p.SearchStats.Guesses += s.Guesses
+		p.SearchStats.Backtracks += s.Backtracks
+		if s.MaxDepth > p.SearchStats.MaxDepth {
+			p.SearchStats.MaxDepth = s.MaxDepth
+		}
+	}
+
+	root := p.Clone()
+	if err := root.DoConstraints(); err != nil {
+		return nil, err
+	}
+	subtrees := root.split(search, rand.New(rand.NewSource(p.GuessSeed)), chooser, orderer,
+		4*workers, found, stats)
+
+	queue := make(chan *subtree)
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			rng := rand.New(rand.NewSource(p.GuessSeed))
+			for s := range queue {
+				s.puzzle.SearchStats = SearchStats{}
+				s.puzzle.enumerate(search, rng, chooser, orderer, s.depth, found)
+				stats(s.puzzle.SearchStats)
+			}
+		}()
+	}
+feed:
+	for _, s := range subtrees {
+		select {
+		case queue <- s:
+		case <-search.Done():
+			break feed
+		}
+	}
+	close(queue)
+	wg.Wait()
+
+	if err := ctx.Err(); err != nil && (limit == 0 || len(solutions) < limit) {
+		return solutions, err
+	}
+	if len(solutions) == 0 {
+		return nil, fmt.Errorf("the puzzle has no solution")
+	}
+	return solutions, nil
+}
+
+// split makes the guesses at the top of the search tree of the puzzle,
+// whose constraints have been done, one level at a time until there are
+// at least count unsolved clones or nothing is left to guess, and
+// returns the unsolved clones.  Solved clones are passed to found.
+func (p *Puzzle) split(ctx context.Context, rng *rand.Rand, chooser CellChooser, orderer ValueOrderer,
+	count int, found func(*Puzzle) bool, stats func(SearchStats)) []*subtree {
+	level := []*subtree{&subtree{puzzle: p}}
+	for len(level) > 0 && len(level) < count {
+		next := []*subtree{}
+		for _, s := range level {
+			unsolved := s.puzzle.unsolvedCells()
+			if len(unsolved) == 0 {
+				if !found(s.puzzle) {
+					return nil
+				}
+				continue
+			}
+			cell := chooser.ChooseCell(s.puzzle, unsolved, rng)
+			for _, value := range orderer.OrderValues(cell, cell.Possibilities.Values(), rng) {
+				if ctx.Err() != nil {
+					return nil
+				}
+				guess := s.puzzle.Clone()
+				ss := SearchStats{Guesses: 1, MaxDepth: s.depth + 1}
+				_, err := guess.Cell(cell.X, cell.Y).MustBe(value, Pick, nil)
+				if err == nil {
+					err = guess.DoConstraints()
+				}
+				if err != nil {
+					ss.Backtracks = 1
+				} else {
+					next = append(next, &subtree{puzzle: guess, depth: s.depth + 1})
+				}
+				stats(ss)
+			}
+		}
+		level = next
+	}
+	return level
+}
+
+// enumerate passes a clone of each solution of the puzzle to found
+// until it returns false or ctx is done, in which case enumerate
+// returns false.  depth is the number of guesses already made.  The
+// Puzzle is left as it was.
+func (p *Puzzle) enumerate(ctx context.Context, rng *rand.Rand, chooser CellChooser, orderer ValueOrderer,
+	depth int, found func(*Puzzle) bool) bool {
+	if ctx.Err() != nil {
+		return false
+	}
+	state := p.saveState()
+	defer p.restoreState(state)
+	if err := p.DoConstraints(); err != nil {
+		return true
+	}
+	unsolved := p.unsolvedCells()
+	if len(unsolved) == 0 {
+		return found(p.Clone())
+	}
+	if depth+1 > p.SearchStats.MaxDepth {
+		p.SearchStats.MaxDepth = depth + 1
+	}
+	cell := chooser.ChooseCell(p, unsolved, rng)
+	for _, value := range orderer.OrderValues(cell, cell.Possibilities.Values(), rng) {
+		guess := p.saveState()
+		p.SearchStats.Guesses += 1
+		more := true
+		if _, err := cell.MustBe(value, Pick, nil); err == nil {
+			more = p.enumerate(ctx, rng, chooser, orderer, depth+1, found)
+		}
+		p.restoreState(guess)
+		if !more {
+			return false
+		}
+		p.SearchStats.Backtracks += 1
+	}
+	return true
+}
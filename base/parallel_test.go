@@ -0,0 +1,91 @@
+package base
+
+import "context"
+import "testing"
+import "time"
+
+func TestSolveContext(t *testing.T) {
+	p := sudokuFromLine(t, hardSudoku)
+	solved := p.Clone()
+	if err := solved.GuessSolve(); err != nil {
+		t.Fatalf("%s", err)
+	}
+	solutions, err := p.SolveContext(context.Background(), SolveOptions{Workers: 4})
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	if len(solutions) != 1 || !solutions[0].IsSolved() {
+		t.Fatalf("Not solved: %d solutions", len(solutions))
+	}
+	for _, c := range solved.Cells() {
+		if want, got := c.Possibilities, solutions[0].Cell(c.X, c.Y).Possibilities; got != want {
+			t.Errorf("Cell(%d, %d): want %s, got %s", c.X, c.Y, want.String(","), got.String(","))
+		}
+	}
+	if p.IsSolved() {
+		t.Errorf("The puzzle was changed")
+	}
+	if p.SearchStats.Guesses == 0 {
+		t.Errorf("No guesses were counted")
+	}
+}
+
+func TestSolveContextAll(t *testing.T) {
+	// Removing the first given leaves several solutions.
+	p := sudokuFromLine(t, "."+hardSudoku[1:])
+	want := p.CountSolutions(0)
+	solutions, err := p.SolveContext(context.Background(), SolveOptions{Workers: 3, All: true})
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	if len(solutions) != want {
+		t.Fatalf("want %d solutions, got %d", want, len(solutions))
+	}
+	seen := make(map[string]bool)
+	for _, s := range solutions {
+		if !s.IsSolved() {
+			t.Fatalf("An unsolved solution")
+		}
+		key := ""
+		for _, c := range s.Cells() {
+			key += c.Possibilities.String("")
+		}
+		if seen[key] {
+			t.Errorf("A solution was found twice")
+		}
+		seen[key] = true
+	}
+	solutions, err = p.SolveContext(context.Background(), SolveOptions{All: true, Limit: 3})
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	if len(solutions) != 3 {
+		t.Errorf("Limit 3: got %d solutions", len(solutions))
+	}
+}
+
+func TestSolveContextCancel(t *testing.T) {
+	// An empty sudoku has far too many solutions to find them all.
+	p := NewEmptySudoku()
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	if _, err := p.SolveContext(ctx, SolveOptions{Workers: 2, All: true}); err != context.DeadlineExceeded {
+		t.Errorf("want %v, got %v", context.DeadlineExceeded, err)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("The search took %s to stop", elapsed)
+	}
+	ctx, cancel = context.WithCancel(context.Background())
+	cancel()
+	if _, err := p.SolveContext(ctx, SolveOptions{}); err != context.Canceled {
+		t.Errorf("want %v, got %v", context.Canceled, err)
+	}
+}
+
+func TestSolveContextNoSolution(t *testing.T) {
+	p := sudokuFromLine(t, "12345678."+"........9"+hardSudoku[18:])
+	if solutions, err := p.SolveContext(context.Background(), SolveOptions{}); err == nil {
+		t.Errorf("No error for a sudoku with no solution, %d solutions", len(solutions))
+	}
+}
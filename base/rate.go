@@ -0,0 +1,59 @@
+// Rate how hard a Puzzle is to solve.
+package base
+
+// Difficulties are the difficulties that Rate can return, from easiest
+// to hardest.
+var Difficulties = []string{"easy", "medium", "hard", "fiendish"}
+
+// Rating describes how a Puzzle was solved.
+type Rating struct {
+	// Difficulty is one of Difficulties.
+	Difficulty string
+	// Passes is the number of times the Constraints of every Group
+	// were applied before constraint propagation stopped making
+	// progress.
+	Passes int
+	// Guesses is the number of guesses that the solution needed,
+	// counting the ones that were undone.
+	Guesses int
+}
+
+// easyPasses is the most passes that an easy puzzle needs.
+const easyPasses = 4
+
+// Rate solves a copy of the Puzzle and rates its difficulty.  A puzzle
+// that constraint propagation solves in a few passes is easy, one that
+// needs more passes is medium, one that needs a guess or two is hard
+// and one that needs more guesses is fiendish.  The Puzzle itself is
+// left as it was.
+func (p *Puzzle) Rate() (*Rating, error) {
+	clone := p.Clone()
+	r := &Rating{}
+	for {
+		tick := clone.Progress
+		for _, g := range clone.Groups {
+			if err := g.DoConstraints(); err != nil {
+				return nil, err
+			}
+		}
+		if clone.Progress == tick {
+			break
+		}
+		r.Passes += 1
+	}
+	if err := clone.GuessSolve(); err != nil {
+		return nil, err
+	}
+	r.Guesses = clone.SearchStats.Guesses
+	switch {
+	case r.Guesses == 0 && r.Passes <= easyPasses:
+		r.Difficulty = Difficulties[0]
+	case r.Guesses == 0:
+		r.Difficulty = Difficulties[1]
+	case r.Guesses <= 2:
+		r.Difficulty = Difficulties[2]
+	default:
+		r.Difficulty = Difficulties[3]
+	}
+	return r, nil
+}
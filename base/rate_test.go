@@ -0,0 +1,37 @@
+package base
+
+import "testing"
+
+func TestRate(t *testing.T) {
+	p := sudokuFromLine(t, hardSudoku)
+	before := p.ValueCount()
+	r, err := p.Rate()
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	if r.Guesses == 0 || r.Difficulty == Difficulties[0] {
+		t.Errorf("Expected guessing, got %#v", r)
+	}
+	if got := p.ValueCount(); got != before {
+		t.Errorf("Rate changed the puzzle")
+	}
+
+	// One unknown in each row is easy.
+	p = sudokuFromLine(t,
+		".23456789"+
+			"4.6789123"+
+			"78.123456"+
+			"234.67891"+
+			"5678.1234"+
+			"89123.567"+
+			"345678.12"+
+			"6789123.5"+
+			"91234567.")
+	r, err = p.Rate()
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	if r.Difficulty != Difficulties[0] || r.Guesses != 0 {
+		t.Errorf("Expected easy, got %#v", r)
+	}
+}
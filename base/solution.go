@@ -0,0 +1,49 @@
+// Checking a solved Puzzle against its known Solution.
+package base
+
+import "fmt"
+
+// Mismatch is a Cell whose possible values don't agree with the
+// Puzzle's Solution.
+type Mismatch struct {
+	Cell *Cell
+	// Expect is the value of the Cell in the Solution.
+	Expect int
+}
+
+func (m *Mismatch) String() string {
+	c := m.Cell
+	if solved, v := c.IsSolved(); solved {
+		return fmt.Sprintf("Cell(%d, %d) is %d rather than %d", c.X, c.Y, v, m.Expect)
+	}
+	if c.Possibilities.IsEmpty() {
+		return fmt.Sprintf("Cell(%d, %d) has no possible values rather than %d", c.X, c.Y, m.Expect)
+	}
+	if c.HasPossibleValue(m.Expect) {
+		return fmt.Sprintf("Cell(%d, %d) could be %s rather than just %d",
+			c.X, c.Y, c.Possibilities.String(", "), m.Expect)
+	}
+	return fmt.Sprintf("Cell(%d, %d) could be %s but not %d",
+		c.X, c.Y, c.Possibilities.String(", "), m.Expect)
+}
+
+// Verify compares the Cells of the Puzzle with its Solution and
+// returns the Cells that aren't solved with the value of the Solution,
+// in row major order.  It's an error for the Puzzle to have no
+// Solution.
+func (p *Puzzle) Verify() ([]*Mismatch, error) {
+	if p.Solution == nil {
+		return nil, fmt.Errorf("the puzzle has no solution to verify against")
+	}
+	mismatches := []*Mismatch{}
+	for y := 1; y <= p.Size; y++ {
+		for x := 1; x <= p.Size; x++ {
+			c := p.Cell(x, y)
+			expect := p.Solution[MakeGridKey(x, y)]
+			if solved, v := c.IsSolved(); !solved || v != expect {
+				mismatches = append(mismatches, &Mismatch{Cell: c, Expect: expect})
+			}
+		}
+	}
+	return mismatches, nil
+}
@@ -0,0 +1,36 @@
+package base
+
+import "testing"
+
+func TestVerify(t *testing.T) {
+	p := &Puzzle{}
+	p.MakeCells(2)
+	p.AddLineGroups()
+	if _, err := p.Verify(); err == nil {
+		t.Errorf("Expected an error for a puzzle without a solution")
+	}
+	p.Solution = map[GridKey]int{
+		MakeGridKey(1, 1): 1, MakeGridKey(2, 1): 2,
+		MakeGridKey(1, 2): 2, MakeGridKey(2, 2): 1,
+	}
+	p.Cell(1, 1).MustBe(2, Given, nil)
+	p.Cell(2, 2).MustBe(1, Given, nil)
+	p.Cell(1, 2).CantBe(2, Given, nil)
+	mismatches, err := p.Verify()
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	expect := []string{
+		"Cell(1, 1) is 2 rather than 1",
+		"Cell(2, 1) could be 1, 2 rather than just 2",
+		"Cell(1, 2) is 1 rather than 2",
+	}
+	if len(mismatches) != len(expect) {
+		t.Fatalf("Expected %d mismatches, got %d", len(expect), len(mismatches))
+	}
+	for i, want := range expect {
+		if got := mismatches[i].String(); got != want {
+			t.Errorf("want %q, got %q", want, got)
+		}
+	}
+}
@@ -69,6 +69,26 @@ func (vs1 ValueSet) SetDifference(vs2 ValueSet) ValueSet {
 	return vs1 & ^vs2
 }
 
+// Min returns the smallest value in the ValueSet, or 0 if it's empty.
+func (vs ValueSet) Min() int {
+	for v := 1; v <= MaxValue; v++ {
+		if vs.HasValue(v) {
+			return v
+		}
+	}
+	return 0
+}
+
+// Max returns the largest value in the ValueSet, or 0 if it's empty.
+func (vs ValueSet) Max() int {
+	for v := MaxValue; v >= 1; v-- {
+		if vs.HasValue(v) {
+			return v
+		}
+	}
+	return 0
+}
+
 // DoValues calls f on each value in the ValueSet.  If f returns false
 // then DoValues doen't call it on further values but returns immediately.
 func (vs ValueSet) DoValues(f func(int) bool) {
@@ -81,6 +101,16 @@ func (vs ValueSet) DoValues(f func(int) bool) {
 	}
 }
 
+// Values returns the values in the ValueSet from smallest to largest.
+func (vs ValueSet) Values() []int {
+	values := []int{}
+	vs.DoValues(func(v int) bool {
+		values = append(values, v)
+		return true
+	})
+	return values
+}
+
 func (vs ValueSet) String(separator string) string {
 	s := ""
 	vs.DoValues(func(v int) bool {
@@ -122,5 +152,5 @@ func (vs ValueSet) MustGet(index int) int {
 	if err != nil {
 		panic(err)
 	}
-    return v
+	return v
 }
@@ -72,3 +72,16 @@ func TestGet(t *testing.T) {
 	test_index(0)
 	test_index(1)
 }
+
+func TestMinMax(t *testing.T) {
+	vs := NewValueSet([]int{3, 5, 8})
+	if got := vs.Min(); got != 3 {
+		t.Errorf("Min: expected 3, got %d", got)
+	}
+	if got := vs.Max(); got != 8 {
+		t.Errorf("Max: expected 8, got %d", got)
+	}
+	if got := NewValueSet([]int{}).Min(); got != 0 {
+		t.Errorf("Min of an empty ValueSet: expected 0, got %d", got)
+	}
+}
@@ -0,0 +1,181 @@
+// Constraints for the common variants of sudoku.
+package base
+
+import "fmt"
+
+// ThermometerConstraint implements the constraint that the values of
+// the Cells of a Group strictly increase from its first Cell, the bulb
+// of the thermometer, to its last.
+var ThermometerConstraint FunctionConstraint
+
+func init() {
+	ThermometerConstraint.name = "ThermometerConstraint"
+	ThermometerConstraint.constraint = func(g *Group) error {
+		cells := g.Cells()
+		// Each cell must be greater than the smallest possible value
+		// of the cell before it...
+		for i := 1; i < len(cells); i++ {
+			least := cells[i-1].Possibilities.Min()
+			for v := 1; v <= least; v++ {
+				if _, err := cells[i].CantBe(v, ThermometerConstraint, g); err != nil {
+					return err
+				}
+			}
+		}
+		// ...and less than the largest possible value of the cell
+		// after it.
+		for i := len(cells) - 2; i >= 0; i-- {
+			greatest := cells[i+1].Possibilities.Max()
+			for v := greatest; v <= MaxValue; v++ {
+				if _, err := cells[i].CantBe(v, ThermometerConstraint, g); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	}
+}
+
+// CageConstraint implements the constraint that the values of the
+// Cells of a Group are all different and, unless sum is 0, add up to
+// sum, as in the cages of a killer sudoku.
+type CageConstraint struct {
+	sum int
+}
+
+// MakeCageConstraint returns a CageConstraint for the specified sum.
+// A sum of 0 means that the cage has no sum.
+func MakeCageConstraint(sum int) Constraint {
+	return &CageConstraint{sum: sum}
+}
+
+func (c *CageConstraint) Name() string {
+	if c.sum == 0 {
+		return "Cage"
+	}
+	return fmt.Sprintf("Cage = %d", c.sum)
+}
+
+// Sum returns the value that the Cells of the cage must add up to, or 0
+// if the cage has no sum.
+func (c *CageConstraint) Sum() int {
+	return c.sum
+}
+
+func (c *CageConstraint) DoConstraint(g *Group) error {
+	cells := g.Cells()
+	// supported[i] collects the values of cells[i] that appear in some
+	// combination of different values with the right sum.
+	supported := make([]ValueSet, len(cells))
+	// Whether the cells after i can be completed only depends on i and
+	// the values used so far, since those determine the partial sum.
+	known := make(map[int]bool)
+	var search func(i int, used ValueSet, sum int) bool
+	search = func(i int, used ValueSet, sum int) bool {
+		if i == len(cells) {
+			return c.sum == 0 || sum == c.sum
+		}
+		key := i<<MaxValue | int(used)
+		if ok, found := known[key]; found {
+			return ok
+		}
+		ok := false
+		cells[i].Possibilities.SetDifference(used).DoValues(func(v int) bool {
+			if c.sum != 0 && sum+v > c.sum {
+				return false
+			}
+			if search(i+1, used.SetHasValue(v, true), sum+v) {
+				supported[i] = supported[i].SetHasValue(v, true)
+				ok = true
+			}
+			return true
+		})
+		known[key] = ok
+		return ok
+	}
+	search(0, 0, 0)
+	for i, cell := range cells {
+		var err error
+		cell.Possibilities.SetDifference(supported[i]).DoValues(func(v int) bool {
+			_, err = cell.CantBe(v, c, g)
+			return err == nil
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ArrowConstraint implements the constraint that the values of the
+// Cells on the shaft of an arrow add up to the number in its circle.
+// The first Cells of the Group are the digits of that number, most
+// significant first, and the rest are the shaft.  Values can repeat
+// along the shaft.
+type ArrowConstraint struct {
+	circle int
+}
+
+// MakeArrowConstraint returns an ArrowConstraint for a circle of the
+// specified number of Cells.
+func MakeArrowConstraint(circle int) Constraint {
+	return &ArrowConstraint{circle: circle}
+}
+
+func (c *ArrowConstraint) Name() string {
+	return "Arrow"
+}
+
+// Circle returns the number of Cells in the arrow's circle.
+func (c *ArrowConstraint) Circle() int {
+	return c.circle
+}
+
+func (c *ArrowConstraint) DoConstraint(g *Group) error {
+	circle := g.Cells()[:c.circle]
+	shaft := g.Cells()[c.circle:]
+	// The smallest and largest numbers that can be in the circle and
+	// that the shaft can add up to.
+	weights := make([]int, len(circle))
+	circle_low, circle_high := 0, 0
+	for i, cell := range circle {
+		weights[i] = 1
+		for j := i + 1; j < len(circle); j++ {
+			weights[i] *= 10
+		}
+		circle_low += weights[i] * cell.Possibilities.Min()
+		circle_high += weights[i] * cell.Possibilities.Max()
+	}
+	shaft_low, shaft_high := 0, 0
+	for _, cell := range shaft {
+		shaft_low += cell.Possibilities.Min()
+		shaft_high += cell.Possibilities.Max()
+	}
+	// Eliminate the values of a cell that would put the range of its
+	// side out of reach of the other side.
+	eliminate := func(cell *Cell, weight int, low int, high int, other_low int, other_high int) error {
+		var err error
+		least := cell.Possibilities.Min()
+		greatest := cell.Possibilities.Max()
+		cell.Possibilities.DoValues(func(v int) bool {
+			l := low - weight*least + weight*v
+			h := high - weight*greatest + weight*v
+			if h < other_low || l > other_high {
+				_, err = cell.CantBe(v, c, g)
+			}
+			return err == nil
+		})
+		return err
+	}
+	for i, cell := range circle {
+		if err := eliminate(cell, weights[i], circle_low, circle_high, shaft_low, shaft_high); err != nil {
+			return err
+		}
+	}
+	for _, cell := range shaft {
+		if err := eliminate(cell, 1, shaft_low, shaft_high, circle_low, circle_high); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,100 @@
+package base
+
+import "testing"
+
+func variantGroup(p *Puzzle, constraint Constraint, cells ...[2]int) *Group {
+	g := NewGroup(p)
+	for _, xy := range cells {
+		g.AddCell(p.Cell(xy[0], xy[1]))
+	}
+	g.AddConstraint(constraint)
+	return g
+}
+
+func TestThermometer(t *testing.T) {
+	p := NewEmptySudoku()
+	g := variantGroup(p, ThermometerConstraint, [2]int{1, 1}, [2]int{2, 1}, [2]int{3, 1}, [2]int{4, 1})
+	p.Cell(3, 1).MustBe(5, Given, nil)
+	if err := g.DoConstraints(); err != nil {
+		t.Fatalf("Error during DoConstraints: %s", err.Error())
+	}
+	expect := []ValueSet{
+		NewValueSet([]int{1, 2, 3}),
+		NewValueSet([]int{2, 3, 4}),
+		NewValueSet([]int{5}),
+		NewValueSet([]int{6, 7, 8, 9}),
+	}
+	for i, want := range expect {
+		if got := g.Cells()[i].Possibilities; got != want {
+			t.Errorf("Cell %d: want %s, got %s", i, want.String(","), got.String(","))
+		}
+	}
+	p.Cell(4, 1).MustBe(6, Given, nil)
+	p.Cell(2, 1).MustBe(4, Given, nil)
+	p.Cell(1, 1).MustBe(3, Given, nil)
+	p.Cell(3, 1).Possibilities = NewValueSet([]int{4, 5})
+	p.Cell(4, 1).Possibilities = NewValueSet([]int{5})
+	if err := g.DoConstraints(); err == nil {
+		t.Errorf("Expected a contradiction")
+	}
+}
+
+func TestCage(t *testing.T) {
+	p := NewEmptySudoku()
+	g := variantGroup(p, MakeCageConstraint(7), [2]int{1, 1}, [2]int{2, 1}, [2]int{2, 2})
+	if err := g.DoConstraints(); err != nil {
+		t.Fatalf("Error during DoConstraints: %s", err.Error())
+	}
+	// The only three different values that add up to 7 are 1, 2 and 4.
+	want := NewValueSet([]int{1, 2, 4})
+	for _, c := range g.Cells() {
+		if c.Possibilities != want {
+			t.Errorf("Cell(%d, %d): want %s, got %s", c.X, c.Y,
+				want.String(","), c.Possibilities.String(","))
+		}
+	}
+	p.Cell(1, 1).MustBe(4, Given, nil)
+	p.Cell(2, 1).MustBe(2, Given, nil)
+	if err := g.DoConstraints(); err != nil {
+		t.Fatalf("Error during DoConstraints: %s", err.Error())
+	}
+	if solved, v := p.Cell(2, 2).IsSolved(); !solved || v != 1 {
+		t.Errorf("Cell(2, 2) should be 1: %s", p.Cell(2, 2).Possibilities.String(","))
+	}
+
+	g = variantGroup(p, MakeCageConstraint(0), [2]int{5, 5}, [2]int{6, 5})
+	p.Cell(5, 5).MustBe(3, Given, nil)
+	if err := g.DoConstraints(); err != nil {
+		t.Fatalf("Error during DoConstraints: %s", err.Error())
+	}
+	if p.Cell(6, 5).HasPossibleValue(3) {
+		t.Errorf("A cage without a sum should still have different values")
+	}
+}
+
+func TestArrow(t *testing.T) {
+	p := NewEmptySudoku()
+	g := variantGroup(p, MakeArrowConstraint(1), [2]int{1, 1}, [2]int{2, 2}, [2]int{3, 3})
+	if err := g.DoConstraints(); err != nil {
+		t.Fatalf("Error during DoConstraints: %s", err.Error())
+	}
+	// A shaft of two cells adds up to at least 2 so the circle can't
+	// be 1 and neither shaft cell can be 9.
+	if p.Cell(1, 1).HasPossibleValue(1) {
+		t.Errorf("The circle can't be 1")
+	}
+	if p.Cell(2, 2).HasPossibleValue(9) || p.Cell(3, 3).HasPossibleValue(9) {
+		t.Errorf("The shaft can't contain a 9")
+	}
+
+	// A circle of two cells holds a two digit number.
+	g = variantGroup(p, MakeArrowConstraint(2),
+		[2]int{1, 9}, [2]int{2, 9}, [2]int{3, 8}, [2]int{4, 7}, [2]int{5, 6})
+	if err := g.DoConstraints(); err != nil {
+		t.Fatalf("Error during DoConstraints: %s", err.Error())
+	}
+	// Three cells add up to at most 27 so the tens digit is 1 or 2.
+	if want, got := NewValueSet([]int{1, 2}), p.Cell(1, 9).Possibilities; got != want {
+		t.Errorf("Tens digit: want %s, got %s", want.String(","), got.String(","))
+	}
+}
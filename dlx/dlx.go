@@ -0,0 +1,279 @@
+// Package dlx solves puzzles whose Groups only require their Cells to
+// have different values, such as sudokus, as an exact cover problem
+// with Knuth's Dancing Links implementation of Algorithm X.  It finds
+// every solution quickly, so it can tell whether a puzzle's solution
+// is unique, but unlike constraint propagation it can't explain them.
+package dlx
+
+import "sudoku/base"
+import "fmt"
+
+// node is a 1 in the sparse exact cover matrix, or the header of a
+// column, or, at index 0, the root that links the primary columns.
+type node struct {
+	left, right, up, down int
+	// column is the header of the node's column.
+	column int
+	// row is the index of the node's row, or -1 for a header.
+	row int
+}
+
+// matrix is an exact cover matrix.  Every primary column must be
+// covered exactly once and every secondary column at most once.
+type matrix struct {
+	nodes []node
+	// size is the number of nodes in each column, indexed by header.
+	size []int
+	rows int
+}
+
+// newMatrix returns an empty matrix with the primary columns 1 to
+// primary and the secondary columns after them.
+func newMatrix(primary, secondary int) *matrix {
+	columns := primary + secondary
+	m := &matrix{
+		nodes: make([]node, columns+1),
+		size:  make([]int, columns+1),
+	}
+	for i := 0; i <= columns; i++ {
+		m.nodes[i] = node{left: i, right: i, up: i, down: i, column: i, row: -1}
+	}
+	for i := 1; i <= primary; i++ {
+		m.nodes[i].left = i - 1
+		m.nodes[i].right = (i + 1) % (primary + 1)
+	}
+	m.nodes[0].left = primary
+	m.nodes[0].right = 1 % (primary + 1)
+	return m
+}
+
+// addRow adds a row with a 1 in each of the columns, which are
+// numbered from 1, and returns its index.
+func (m *matrix) addRow(columns []int) int {
+	row := m.rows
+	m.rows += 1
+	first := -1
+	for _, c := range columns {
+		i := len(m.nodes)
+		n := node{column: c, row: row, up: m.nodes[c].up, down: c}
+		if first < 0 {
+			first = i
+			n.left, n.right = i, i
+		} else {
+			n.left, n.right = m.nodes[first].left, first
+			m.nodes[m.nodes[first].left].right = i
+			m.nodes[first].left = i
+		}
+		m.nodes = append(m.nodes, n)
+		m.nodes[m.nodes[c].up].down = i
+		m.nodes[c].up = i
+		m.size[c] += 1
+	}
+	return row
+}
+
+func (m *matrix) cover(c int) {
+	n := m.nodes
+	n[n[c].right].left = n[c].left
+	n[n[c].left].right = n[c].right
+	for i := n[c].down; i != c; i = n[i].down {
+		for j := n[i].right; j != i; j = n[j].right {
+			n[n[j].down].up = n[j].up
+			n[n[j].up].down = n[j].down
+			m.size[n[j].column] -= 1
+		}
+	}
+}
+
+func (m *matrix) uncover(c int) {
+	n := m.nodes
+	for i := n[c].up; i != c; i = n[i].up {
+		for j := n[i].left; j != i; j = n[j].left {
+			m.size[n[j].column] += 1
+			n[n[j].down].up = j
+			n[n[j].up].down = j
+		}
+	}
+	n[n[c].right].left = c
+	n[n[c].left].right = c
+}
+
+// search calls found with the rows of each exact cover until it
+// returns false.  It returns false if the search was stopped.
+func (m *matrix) search(rows []int, found func(rows []int) bool) bool {
+	n := m.nodes
+	if n[0].right == 0 {
+		return found(rows)
+	}
+	// The primary column with the fewest rows.
+	best := n[0].right
+	for c := n[best].right; c != 0; c = n[c].right {
+		if m.size[c] < m.size[best] {
+			best = c
+		}
+	}
+	if m.size[best] == 0 {
+		return true
+	}
+	m.cover(best)
+	defer m.uncover(best)
+	for i := n[best].down; i != best; i = n[i].down {
+		for j := n[i].right; j != i; j = n[j].right {
+			m.cover(n[j].column)
+		}
+		more := m.search(append(rows, n[i].row), found)
+		for j := n[i].left; j != i; j = n[j].left {
+			m.uncover(n[j].column)
+		}
+		if !more {
+			return false
+		}
+	}
+	return true
+}
+
+// choice is what a row of the matrix means: the Cell has the value.
+type choice struct {
+	cell  *base.Cell
+	value int
+}
+
+// encode returns the exact cover matrix of the puzzle and the choice
+// that each of its rows stands for.  Each Cell has a primary column,
+// so that it has exactly one value.  Each Group that needs different
+// values has a column for each value: a primary one if the Group has as
+// many Cells as there are values, so that each value must be in it
+// once, and a secondary one otherwise, so that each value can be in it
+// at most once.  It's an error for a Group to have any other
+// Constraint.
+func encode(p *base.Puzzle) (*matrix, []choice, error) {
+	values := p.Universe.Values()
+	cells := p.Cells()
+	primary := []*base.Group{}
+	secondary := []*base.Group{}
+	for _, g := range p.Groups {
+		different, every := false, false
+		for _, c := range g.Constraints() {
+			switch c.Name() {
+			case base.HereThenNotElsewhereConstraint.Name():
+				different = true
+			case base.NotElsewhereThenHereConstraint.Name():
+				every = true
+			default:
+				if cage, ok := c.(*base.CageConstraint); ok && cage.Sum() == 0 {
+					different = true
+					continue
+				}
+				return nil, nil, fmt.Errorf("group %s has the constraint %s, which isn't an exact cover",
+					g.Label(), c.Name())
+			}
+		}
+		full := len(g.Cells()) == len(values)
+		switch {
+		case full && (different || every):
+			primary = append(primary, g)
+		case every:
+			return nil, nil, fmt.Errorf("group %s has %d cells, too few for every value", g.Label(), len(g.Cells()))
+		case different:
+			secondary = append(secondary, g)
+		}
+	}
+	m := newMatrix(len(cells)+len(primary)*len(values), len(secondary)*len(values))
+	group_columns := make(map[*base.Group]int)
+	next := len(cells) + 1
+	for _, g := range append(append([]*base.Group{}, primary...), secondary...) {
+		group_columns[g] = next
+		next += len(values)
+	}
+	value_index := make(map[int]int)
+	for i, v := range values {
+		value_index[v] = i
+	}
+	choices := []choice{}
+	for i, c := range cells {
+		for _, v := range c.Possibilities.Values() {
+			columns := []int{i + 1}
+			for _, g := range c.Groups {
+				if column, found := group_columns[g]; found {
+					columns = append(columns, column+value_index[v])
+				}
+			}
+			m.addRow(columns)
+			choices = append(choices, choice{cell: c, value: v})
+		}
+	}
+	return m, choices, nil
+}
+
+// Solutions returns the solutions of the puzzle, no more than limit of
+// them unless limit is 0, each giving the value of every Cell.  The
+// puzzle's Groups must only require different values.
+func Solutions(p *base.Puzzle, limit int) ([]map[base.GridKey]int, error) {
+	m, choices, err := encode(p)
+	if err != nil {
+		return nil, err
+	}
+	solutions := []map[base.GridKey]int{}
+	m.search(nil, func(rows []int) bool {
+		solution := make(map[base.GridKey]int)
+		for _, r := range rows {
+			solution[base.MakeGridKey(choices[r].cell.X, choices[r].cell.Y)] = choices[r].value
+		}
+		solutions = append(solutions, solution)
+		return limit == 0 || len(solutions) < limit
+	})
+	return solutions, nil
+}
+
+// CountSolutions returns the number of solutions of the puzzle,
+// counting no further than limit unless limit is 0.  A limit of 2 is
+// enough to tell whether the solution is unique.
+func CountSolutions(p *base.Puzzle, limit int) (int, error) {
+	m, _, err := encode(p)
+	if err != nil {
+		return 0, err
+	}
+	count := 0
+	m.search(nil, func(rows []int) bool {
+		count += 1
+		return limit == 0 || count < limit
+	})
+	return count, nil
+}
+
+// exactCover is the Constraint that justifies what Solve deduces.
+type exactCover struct{}
+
+func (exactCover) Name() string                     { return "ExactCover" }
+func (exactCover) DoConstraint(g *base.Group) error { return nil }
+
+// ExactCover is the Constraint of the Justifications that Solve makes.
+var ExactCover base.Constraint = exactCover{}
+
+// Solve finds the solutions of the puzzle, no more than limit of them
+// unless limit is 0, and removes from each Cell's Possibilities the
+// values it doesn't have in any of them, justified by ExactCover.  A
+// limit of 1 solves the puzzle like GuessSolve, and a limit of 0 leaves
+// each Cell with exactly the values it can have.  It returns the number
+// of solutions found.  It's an error for there to be none.
+func Solve(p *base.Puzzle, limit int) (int, error) {
+	solutions, err := Solutions(p, limit)
+	if err != nil {
+		return 0, err
+	}
+	if len(solutions) == 0 {
+		return 0, fmt.Errorf("the puzzle has no solution")
+	}
+	for _, c := range p.Cells() {
+		var possible base.ValueSet
+		for _, s := range solutions {
+			possible = possible.SetHasValue(s[base.MakeGridKey(c.X, c.Y)], true)
+		}
+		for _, v := range c.Possibilities.SetDifference(possible).Values() {
+			if _, err := c.CantBe(v, ExactCover, nil); err != nil {
+				return len(solutions), err
+			}
+		}
+	}
+	return len(solutions), nil
+}
@@ -0,0 +1,92 @@
+package dlx
+
+import "sudoku/base"
+import "sudoku/puzzletest"
+import "testing"
+
+func TestSolutions(t *testing.T) {
+	p := puzzletest.SudokuFromLine(t, puzzletest.HardSudoku)
+	solutions, err := Solutions(p, 0)
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	if len(solutions) != 1 {
+		t.Fatalf("want 1 solution, got %d", len(solutions))
+	}
+	for i, ch := range puzzletest.HardSolution {
+		if want, got := int(ch-'0'), solutions[0][base.MakeGridKey(i%9+1, i/9+1)]; got != want {
+			t.Errorf("Cell(%d, %d): want %d, got %d", i%9+1, i/9+1, want, got)
+		}
+	}
+}
+
+func TestCountSolutions(t *testing.T) {
+	// Removing the first given leaves several solutions.
+	p := puzzletest.SudokuFromLine(t, "."+puzzletest.HardSudoku[1:])
+	for _, limit := range []int{1, 2, 5} {
+		count, err := CountSolutions(p, limit)
+		if err != nil {
+			t.Fatalf("%s", err)
+		}
+		if count != limit {
+			t.Errorf("limit %d: got %d solutions", limit, count)
+		}
+	}
+	all, err := CountSolutions(p, 0)
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	if want, got := all, p.CountSolutions(0); got != want {
+		t.Errorf("want %d solutions like Puzzle.CountSolutions, got %d", want, got)
+	}
+	empty, err := CountSolutions(base.NewEmptySudoku(), 1000)
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	if empty != 1000 {
+		t.Errorf("An empty sudoku: want 1000 solutions, got %d", empty)
+	}
+}
+
+func TestSolve(t *testing.T) {
+	p := puzzletest.SudokuFromLine(t, puzzletest.HardSudoku)
+	count, err := Solve(p, 0)
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	if count != 1 || !p.IsSolved() {
+		t.Fatalf("Not solved: %d solutions", count)
+	}
+	if got := p.Cell(2, 1).Possibilities.MustGet(0); got != 1 {
+		t.Errorf("Cell(2, 1): want 1, got %d", got)
+	}
+	// Solving leaves the values each Cell can have in some solution.
+	p = puzzletest.SudokuFromLine(t, "."+puzzletest.HardSudoku[1:])
+	count, err = Solve(p, 0)
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	if count < 2 || p.IsSolved() {
+		t.Fatalf("%d solutions, solved %v", count, p.IsSolved())
+	}
+	if !p.Cell(1, 1).Possibilities.HasValue(8) {
+		t.Errorf("Cell(1, 1) can't be 8")
+	}
+	// A contradiction.
+	p = puzzletest.SudokuFromLine(t, "12345678."+"........9"+puzzletest.HardSudoku[18:])
+	if _, err := Solve(p, 0); err == nil {
+		t.Errorf("Solved a sudoku with no solution")
+	}
+}
+
+func TestUnsupportedConstraint(t *testing.T) {
+	p := base.NewEmptySudoku()
+	g := base.NewGroup(p).SetLabel("cage")
+	g.AddCell(p.Cell(1, 1))
+	g.AddCell(p.Cell(2, 1))
+	g.AddConstraint(base.MakeCageConstraint(10))
+	p.Groups = append(p.Groups, g)
+	if _, err := CountSolutions(p, 2); err == nil {
+		t.Errorf("No error for a killer cage with a sum")
+	}
+}
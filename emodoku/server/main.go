@@ -4,6 +4,7 @@
 // artistically convey a symbolic message.
 package main
 
+import "context"
 import "encoding/json"
 import "flag"
 import "fmt"
@@ -15,10 +16,11 @@ import "os"
 import "path/filepath"
 import "sudoku/base"
 import "sudoku/text"
+import "time"
 
 var upgrader = websocket.Upgrader{
-	ReadBufferSize:    4096,
-	WriteBufferSize:   4096,
+	ReadBufferSize:  4096,
+	WriteBufferSize: 4096,
 	// ***** Should replace this with something safer some day.
 	CheckOrigin: func(r *http.Request) bool {
 		return true
@@ -40,6 +42,9 @@ var jsFile = flag.String("jsFile", "emodoku.js",
 var makeSudoku = flag.String("makeSudoku", "make_sudoku.html",
 	"The path to the HTML page that implements the service.")
 
+var solveTimeout = flag.Duration("solve-timeout", 5*time.Second,
+	"How long to search for the solutions of a puzzle before giving up.")
+
 func main() {
 	flag.Parse()
 	http.HandleFunc("/", makeFileResponder(*topStaticFile))
@@ -64,7 +69,7 @@ func makeFileResponder(file string) func(http.ResponseWriter, *http.Request) {
 	if err != nil {
 		panic(err)
 	}
-	return func (w http.ResponseWriter, r *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
 		log.Printf("ServeFile %s", file)
 		http.ServeFile(w, r, file)
 	}
@@ -76,27 +81,45 @@ func handleSolver(w http.ResponseWriter, r *http.Request) {
 		log.Printf("Error upgrading to websocket: %s", err)
 		return
 	}
-	for {
-		messageType, r, err := conn.NextReader()
-		if err != nil {
-			log.Printf("conn.NextReader: %s", err)
-			return
-		}
-		if messageType != websocket.TextMessage  {
-			log.Printf("Received unsupported message type %d", messageType)
-			continue
-		}
-		msg, err := ioutil.ReadAll(r)
-		if err != nil {
-			log.Printf("Error reading message: %s", err)
-			continue
+	// The request's context isn't cancelled when the websocket is
+	// closed, so the messages are read by a goroutine of their own,
+	// which cancels ctx when it can't read any more.  That stops a
+	// search for a browser that has gone away.
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	messages := make(chan []byte)
+	go func() {
+		defer close(messages)
+		defer cancel()
+		for {
+			messageType, r, err := conn.NextReader()
+			if err != nil {
+				log.Printf("conn.NextReader: %s", err)
+				return
+			}
+			if messageType != websocket.TextMessage {
+				log.Printf("Received unsupported message type %d", messageType)
+				continue
+			}
+			msg, err := ioutil.ReadAll(r)
+			if err != nil {
+				log.Printf("Error reading message: %s", err)
+				continue
+			}
+			select {
+			case messages <- msg:
+			case <-ctx.Done():
+				return
+			}
 		}
+	}()
+	for msg := range messages {
 		puzzle, err := text.TextToSudoku(string(msg))
 		if err != nil {
 			log.Printf("Error parsing sudoku from text: %s", err)
 			continue
 		}
-		response := MakeSolutionResponse(puzzle)
+		response := MakeSolutionResponse(ctx, puzzle)
 		encoded, err := json.MarshalIndent(response, "", "")
 		if err != nil {
 			log.Printf("Error ncoding JSON: %s", err)
@@ -105,7 +128,7 @@ func handleSolver(w http.ResponseWriter, r *http.Request) {
 		// log.Printf("Solver response:\n%s", encoded)
 		w, err := conn.NextWriter(websocket.TextMessage)
 		if err != nil {
-			log.Printf("NextWriter error: %s",err)
+			log.Printf("NextWriter error: %s", err)
 			continue
 		}
 		_, err = w.Write(encoded)
@@ -130,11 +153,29 @@ type SolutionResponse struct {
 	// Error will be the empty string if no error occurred while the puzzle
 	// was being solved, otherwise it is a string describing the error.
 	Error string
+	// Solutions is the number of solutions that were found, no more
+	// than 2, so 1 means that the solution is unique unless Error says
+	// that the search was stopped.
+	Solutions uint
 }
 
-func MakeSolutionResponse(p *base.Puzzle) *SolutionResponse {
+// MakeSolutionResponse describes what constraint propagation deduces
+// about the puzzle, and searches for its solutions until there's more
+// than one, ctx is done or -solve-timeout passes.
+func MakeSolutionResponse(ctx context.Context, p *base.Puzzle) *SolutionResponse {
 	errMsg := ""
+	solutions := 0
 	err := p.DoConstraints()
+	if err == nil {
+		ctx, cancel := context.WithTimeout(ctx, *solveTimeout)
+		defer cancel()
+		var found []*base.Puzzle
+		found, err = p.SolveContext(ctx, base.SolveOptions{All: true, Limit: 2})
+		solutions = len(found)
+		if err == context.DeadlineExceeded {
+			err = fmt.Errorf("The search for solutions took more than %s", *solveTimeout)
+		}
+	}
 	if err != nil {
 		errMsg = err.Error()
 	}
@@ -142,7 +183,7 @@ func MakeSolutionResponse(p *base.Puzzle) *SolutionResponse {
 	for row := 0; row < p.Size; row++ {
 		grid[row] = make([][]uint, p.Size)
 		for col := 0; col < p.Size; col++ {
-			cell := p.Cell(col + 1, row + 1)
+			cell := p.Cell(col+1, row+1)
 			for val := 1; val <= p.Size; val++ {
 				if cell.Possibilities.HasValue(val) {
 					grid[row][col] = append(grid[row][col], uint(val))
@@ -151,8 +192,9 @@ func MakeSolutionResponse(p *base.Puzzle) *SolutionResponse {
 		}
 	}
 	return &SolutionResponse{
-		Size: uint(p.Size),
+		Size:          uint(p.Size),
 		Possibilities: grid,
-		Error: errMsg,
+		Error:         errMsg,
+		Solutions:     uint(solutions),
 	}
 }
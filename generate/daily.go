@@ -0,0 +1,56 @@
+package generate
+
+import "sudoku/base"
+import "fmt"
+import "hash/fnv"
+import "time"
+
+// DateFormat is the format of the dates that Daily reads.
+const DateFormat = "2006-01-02"
+
+// Schedule is the difficulty of the daily puzzle on each day of the
+// week, starting with Sunday.
+var Schedule = [7]string{"hard", "easy", "easy", "medium", "medium", "hard", "fiendish"}
+
+// StringSeed returns the seed of the random numbers for a string, so
+// that any string can name a puzzle.
+func StringSeed(s string) int64 {
+	h := fnv.New64a()
+	h.Write([]byte(s))
+	return int64(h.Sum64())
+}
+
+// DailyOptions returns the Options of the puzzle named by seed.  If
+// seed is a date in DateFormat its difficulty is the Schedule's for
+// that day of the week.  Any other string picks a day of the Schedule
+// from its StringSeed.
+func DailyOptions(seed string) Options {
+	n := StringSeed(seed)
+	day := int(uint64(n) % 7)
+	if date, err := time.Parse(DateFormat, seed); err == nil {
+		day = int(date.Weekday())
+	}
+	return Options{
+		Seed:       n,
+		Difficulty: Schedule[day],
+		Symmetry:   Rotational,
+	}
+}
+
+// Daily returns the puzzle named by seed, usually a date, with
+// DailyOptions.  It's the same puzzle every time, so that past days'
+// puzzles can be made again: everything that generating a puzzle does
+// is in an order that depends only on the seed, never on the order of
+// a map.  The puzzle's "title" Metadata names the seed.
+func Daily(seed string) (*base.Puzzle, error) {
+	p, err := Sudoku(DailyOptions(seed))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %s", seed, err)
+	}
+	title := seed
+	if date, err := time.Parse(DateFormat, seed); err == nil {
+		title = date.Format("Monday 2 January 2006")
+	}
+	p.SetMetadata("title", "Sudoku for "+title)
+	return p, nil
+}
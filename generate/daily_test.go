@@ -0,0 +1,49 @@
+package generate
+
+import "testing"
+
+func TestDaily(t *testing.T) {
+	// A Monday, which is easy and quick to generate.
+	p1, err := Daily("2026-10-19")
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	if p1.Metadata["difficulty"] != "easy" {
+		t.Errorf("Expected an easy puzzle on a Monday, got %s", p1.Metadata["difficulty"])
+	}
+	if p1.Metadata["title"] != "Sudoku for Monday 19 October 2026" {
+		t.Errorf("Unexpected title %q", p1.Metadata["title"])
+	}
+	p2, err := Daily("2026-10-19")
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	for y := 1; y <= 9; y++ {
+		for x := 1; x <= 9; x++ {
+			given1, v1 := p1.Cell(x, y).IsGiven()
+			given2, v2 := p2.Cell(x, y).IsGiven()
+			if given1 != given2 || v1 != v2 {
+				t.Fatalf("The same day made different puzzles at (%d, %d)", x, y)
+			}
+		}
+	}
+}
+
+func TestDailyOptions(t *testing.T) {
+	for date, difficulty := range map[string]string{
+		"2026-10-18": "hard",
+		"2026-10-19": "easy",
+		"2026-10-21": "medium",
+		"2026-10-24": "fiendish",
+	} {
+		if got := DailyOptions(date).Difficulty; got != difficulty {
+			t.Errorf("%s: expected %s, got %s", date, difficulty, got)
+		}
+	}
+	if DailyOptions("newsletter 12").Seed != DailyOptions("newsletter 12").Seed {
+		t.Errorf("The same string gave different seeds")
+	}
+	if DailyOptions("newsletter 12").Seed == DailyOptions("newsletter 13").Seed {
+		t.Errorf("Different strings gave the same seed")
+	}
+}
@@ -0,0 +1,237 @@
+// Package generate makes new puzzles with unique solutions.
+package generate
+
+import "sudoku/base"
+import "fmt"
+import "math/rand"
+
+// Symmetry is how the givens of a generated puzzle are arranged.
+type Symmetry int
+
+const (
+	// NoSymmetry places the givens anywhere.
+	NoSymmetry Symmetry = iota
+	// Rotational places the givens so that the grid looks the same
+	// when it's turned half way around.
+	Rotational
+	// Mirror places the givens so that the right half of the grid is
+	// the reflection of the left half.
+	Mirror
+)
+
+var symmetryNames = []string{
+	NoSymmetry: "none",
+	Rotational: "rotational",
+	Mirror:     "mirror",
+}
+
+func (s Symmetry) String() string {
+	return symmetryNames[s]
+}
+
+// ParseSymmetry returns the Symmetry with the specified name: none,
+// rotational or mirror.
+func ParseSymmetry(name string) (Symmetry, error) {
+	for s, n := range symmetryNames {
+		if n == name {
+			return Symmetry(s), nil
+		}
+	}
+	return NoSymmetry, fmt.Errorf("%q isn't a symmetry, which can be none, rotational or mirror", name)
+}
+
+// Options control what Sudoku generates.
+type Options struct {
+	// Seed seeds the random numbers, so the same Options generate the
+	// same puzzle.
+	Seed int64
+	// Difficulty, if not "", is the base.Difficulties rating that the
+	// puzzle must have.
+	Difficulty string
+	Symmetry   Symmetry
+	// Givens, if not 0, is the number of givens to stop at rather than
+	// removing as many as possible.  The puzzle can have more givens if
+	// no more can be removed.
+	Givens int
+	// Mask, if not nil, marks the cells that the givens must be in,
+	// rather than removing givens.  Symmetry and Givens can't be used
+	// with a Mask.
+	Mask map[base.GridKey]bool
+	// Attempts is how many full grids to try before giving up on
+	// meeting the Difficulty or the Mask.  0 means 100.
+	Attempts int
+}
+
+// Sudoku makes a sudoku with a unique solution.  It fills a grid at
+// random and then removes givens, in a random order that keeps the
+// Symmetry, for as long as the solution stays unique, the puzzle is no
+// harder than the Difficulty and there are more than Givens.  If the
+// puzzle ends up easier than the Difficulty, it starts again with a new
+// grid.  With a Mask, the values of the givens on the mask are changed
+// one at a time until the solution is unique or it's time to start
+// again with a new grid.  The puzzle's Solution is the full grid and
+// its "difficulty" Metadata is its rating.
+func Sudoku(options Options) (*base.Puzzle, error) {
+	target := -1
+	if options.Difficulty != "" {
+		target = difficultyIndex(options.Difficulty)
+		if target < 0 {
+			return nil, fmt.Errorf("%q isn't one of the difficulties %v", options.Difficulty, base.Difficulties)
+		}
+	}
+	if options.Mask != nil {
+		if options.Symmetry != NoSymmetry || options.Givens != 0 {
+			return nil, fmt.Errorf("a mask can't be used with a symmetry or a number of givens")
+		}
+		if err := checkMask(options.Mask); err != nil {
+			return nil, err
+		}
+	}
+	attempts := options.Attempts
+	if attempts == 0 {
+		attempts = 100
+	}
+	rng := rand.New(rand.NewSource(options.Seed))
+	for attempt := 0; attempt < attempts; attempt++ {
+		grid := fullGrid(rng)
+		var givens map[base.GridKey]int
+		if options.Mask != nil {
+			givens, grid = maskGivens(rng, grid, options.Mask)
+			if givens == nil {
+				continue
+			}
+		} else {
+			givens = removeGivens(rng, grid, options, target)
+		}
+		p := sudokuFromGivens(givens)
+		rating, err := p.Rate()
+		if err != nil {
+			return nil, err
+		}
+		if target >= 0 && difficultyIndex(rating.Difficulty) != target {
+			continue
+		}
+		p.Solution = grid
+		p.SetMetadata("difficulty", rating.Difficulty)
+		return p, nil
+	}
+	description := "sudoku"
+	if options.Difficulty != "" {
+		description = options.Difficulty + " " + description
+	}
+	if options.Mask != nil {
+		description += " with givens on the mask"
+	}
+	return nil, fmt.Errorf("no %s was found in %d attempts", description, attempts)
+}
+
+// removeGivens starts with all of the grid as givens and removes them,
+// as Sudoku describes.
+func removeGivens(rng *rand.Rand, grid map[base.GridKey]int, options Options, target int) map[base.GridKey]int {
+	givens := make(map[base.GridKey]int)
+	for y := 1; y <= 9; y++ {
+		for x := 1; x <= 9; x++ {
+			key := base.MakeGridKey(x, y)
+			givens[key] = grid[key]
+		}
+	}
+	for _, orbit := range orbits(rng, options.Symmetry) {
+		if options.Givens > 0 && len(givens)-len(orbit) < options.Givens {
+			continue
+		}
+		removed := []int{}
+		for _, key := range orbit {
+			removed = append(removed, givens[key])
+			delete(givens, key)
+		}
+		p := sudokuFromGivens(givens)
+		keep := p.CountSolutions(2) != 1
+		if !keep && target >= 0 {
+			rating, err := p.Rate()
+			keep = err != nil || difficultyIndex(rating.Difficulty) > target
+		}
+		if keep {
+			for i, key := range orbit {
+				givens[key] = removed[i]
+			}
+		}
+	}
+	return givens
+}
+
+func difficultyIndex(difficulty string) int {
+	for i, d := range base.Difficulties {
+		if d == difficulty {
+			return i
+		}
+	}
+	return -1
+}
+
+// fullGrid returns the values of a random solved sudoku.  The three
+// boxes on the diagonal, which don't constrain each other, are filled
+// at random and the rest is solved.
+func fullGrid(rng *rand.Rand) map[base.GridKey]int {
+	p := base.NewEmptySudoku()
+	for box := 0; box < 3; box++ {
+		for i, v := range rng.Perm(9) {
+			p.Cell(box*3+i%3+1, box*3+i/3+1).MustBe(v+1, base.Given, nil)
+		}
+	}
+	if err := p.GuessSolve(); err != nil {
+		// Any values in the diagonal boxes can be completed.
+		panic(err)
+	}
+	grid := make(map[base.GridKey]int)
+	for y := 1; y <= 9; y++ {
+		for x := 1; x <= 9; x++ {
+			_, grid[base.MakeGridKey(x, y)] = p.Cell(x, y).IsSolved()
+		}
+	}
+	return grid
+}
+
+// orbits returns the cells of a sudoku in a random order, grouped into
+// the sets of cells that the symmetry maps onto each other.
+func orbits(rng *rand.Rand, symmetry Symmetry) [][]base.GridKey {
+	seen := make(map[base.GridKey]bool)
+	result := [][]base.GridKey{}
+	for _, i := range rng.Perm(81) {
+		key := base.MakeGridKey(i%9+1, i/9+1)
+		if seen[key] {
+			continue
+		}
+		orbit := []base.GridKey{key}
+		var other base.GridKey
+		switch symmetry {
+		case Rotational:
+			other = base.MakeGridKey(10-key.X, 10-key.Y)
+		case Mirror:
+			other = base.MakeGridKey(10-key.X, key.Y)
+		default:
+			other = key
+		}
+		if other != key {
+			orbit = append(orbit, other)
+		}
+		for _, k := range orbit {
+			seen[k] = true
+		}
+		result = append(result, orbit)
+	}
+	return result
+}
+
+// sudokuFromGivens returns an unsolved sudoku with the givens, which
+// are set in row major order.
+func sudokuFromGivens(givens map[base.GridKey]int) *base.Puzzle {
+	p := base.NewEmptySudoku()
+	for y := 1; y <= 9; y++ {
+		for x := 1; x <= 9; x++ {
+			if v, found := givens[base.MakeGridKey(x, y)]; found {
+				p.Cell(x, y).MustBe(v, base.Given, nil)
+			}
+		}
+	}
+	return p
+}
@@ -0,0 +1,80 @@
+package generate
+
+import "sudoku/base"
+import "testing"
+
+func TestSudoku(t *testing.T) {
+	p, err := Sudoku(Options{Seed: 1})
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	if got := p.CountSolutions(0); got != 1 {
+		t.Errorf("Expected a unique solution, got %d", got)
+	}
+	if err := p.GuessSolve(); err != nil {
+		t.Fatalf("%s", err)
+	}
+	if mismatches, err := p.Verify(); err != nil || len(mismatches) > 0 {
+		t.Errorf("The Solution isn't the solution: %v %v", mismatches, err)
+	}
+	if p.Metadata["difficulty"] == "" {
+		t.Errorf("The difficulty wasn't rated")
+	}
+
+	again, err := Sudoku(Options{Seed: 1})
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	g1, g2 := givens(p), givens(again)
+	if len(g1) != len(g2) {
+		t.Fatalf("The same seed made different puzzles")
+	}
+	for key, v := range g1 {
+		if g2[key] != v {
+			t.Fatalf("The same seed made different puzzles")
+		}
+	}
+}
+
+func TestSymmetry(t *testing.T) {
+	for _, symmetry := range []Symmetry{Rotational, Mirror} {
+		p, err := Sudoku(Options{Seed: 2, Symmetry: symmetry})
+		if err != nil {
+			t.Fatalf("%s", err)
+		}
+		g := givens(p)
+		for key := range g {
+			other := base.MakeGridKey(10-key.X, 10-key.Y)
+			if symmetry == Mirror {
+				other = base.MakeGridKey(10-key.X, key.Y)
+			}
+			if _, found := g[other]; !found {
+				t.Errorf("%s: (%d, %d) is a given but (%d, %d) isn't",
+					symmetry, key.X, key.Y, other.X, other.Y)
+			}
+		}
+	}
+	if _, err := ParseSymmetry("diagonal"); err == nil {
+		t.Errorf("Expected an error for an unknown symmetry")
+	}
+}
+
+func TestGivensAndDifficulty(t *testing.T) {
+	p, err := Sudoku(Options{Seed: 3, Givens: 40})
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	if got := len(givens(p)); got != 40 {
+		t.Errorf("Expected 40 givens, got %d", got)
+	}
+	p, err = Sudoku(Options{Seed: 3, Difficulty: "easy"})
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	if rating, err := p.Rate(); err != nil || rating.Difficulty != "easy" {
+		t.Errorf("Expected an easy puzzle: %v %v", rating, err)
+	}
+	if _, err := Sudoku(Options{Difficulty: "trivial"}); err == nil {
+		t.Errorf("Expected an error for an unknown difficulty")
+	}
+}
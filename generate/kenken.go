@@ -0,0 +1,362 @@
+package generate
+
+import "sudoku/base"
+import "sudoku/text"
+import "fmt"
+import "math/rand"
+import "sort"
+import "strings"
+
+// DefaultCageSizes is the cage size distribution used when
+// KenKenOptions don't give one.
+var DefaultCageSizes = []int{1, 5, 4, 2}
+
+// KenKenOptions control what KenKen generates.
+type KenKenOptions struct {
+	// Size is the number of rows and columns, from 3 to 9.
+	Size int
+	// Seed seeds the random numbers, so the same KenKenOptions
+	// generate the same puzzle.
+	Seed int64
+	// CageSizes are the relative weights of the cage sizes:
+	// CageSizes[i] is the weight of cages of i + 1 cells.  nil means
+	// DefaultCageSizes.  The largest cage size must be big enough for
+	// the grid to need no more than len(text.CageLetters) cages.
+	CageSizes []int
+	// Attempts is how many Latin squares to try before giving up.  0
+	// means 100.
+	Attempts int
+}
+
+// kenkenCage is a cage of a KenKen being generated.
+type kenkenCage struct {
+	cells  []base.GridKey
+	symbol string
+	target int
+	// reworked is true once the rule of the cage has been picked again
+	// because the puzzle's solution wasn't unique.
+	reworked bool
+}
+
+// kenkenGenerator holds the state of KenKen.
+type kenkenGenerator struct {
+	rng   *rand.Rand
+	size  int
+	grid  map[base.GridKey]int
+	cages []*kenkenCage
+}
+
+// KenKen makes a KenKen with a unique solution.  It fills a random
+// Latin square, splits it into contiguous cages whose sizes follow the
+// CageSizes distribution and picks an operator and target for each
+// cage from its values.  While the solution isn't unique, a cage with
+// a cell that the constraints can't solve is given a different rule
+// or, if that was already tried, split up.  It returns the puzzle,
+// whose Solution is the Latin square, and its text in TextToKenKen's
+// format.
+func KenKen(options KenKenOptions) (*base.Puzzle, string, error) {
+	if options.Size < 3 || options.Size > 9 {
+		return nil, "", fmt.Errorf("the size of a KenKen must be from 3 to 9, not %d", options.Size)
+	}
+	weights := options.CageSizes
+	if weights == nil {
+		weights = DefaultCageSizes
+	}
+	total := 0
+	for _, w := range weights {
+		if w < 0 {
+			return nil, "", fmt.Errorf("the cage size weights %v can't be negative", weights)
+		}
+		total += w
+	}
+	if total == 0 {
+		return nil, "", fmt.Errorf("the cage size weights %v are all 0", weights)
+	}
+	largest := 0
+	for i, w := range weights {
+		if w > 0 {
+			largest = i + 1
+		}
+	}
+	cells := options.Size * options.Size
+	if need := (cells + largest - 1) / largest; need > len(text.CageLetters) {
+		return nil, "", fmt.Errorf("a %dx%d KenKen with cages of at most %d cells needs %d cages, but the text format can only name %d",
+			options.Size, options.Size, largest, need, len(text.CageLetters))
+	}
+	attempts := options.Attempts
+	if attempts == 0 {
+		attempts = 100
+	}
+	rng := rand.New(rand.NewSource(options.Seed))
+	for attempt := 0; attempt < attempts; attempt++ {
+		g := &kenkenGenerator{
+			rng:  rng,
+			size: options.Size,
+			grid: latinSquare(rng, options.Size),
+		}
+		g.partition(weights, total)
+		for _, cage := range g.cages {
+			g.pickRule(cage)
+		}
+		for len(g.cages) <= len(text.CageLetters) {
+			p, written, err := g.puzzle()
+			if err != nil {
+				return nil, "", err
+			}
+			if p.CountSolutions(2) == 1 {
+				return p, written, nil
+			}
+			g.rework(p)
+		}
+	}
+	return nil, "", fmt.Errorf("no %dx%d KenKen was found in %d attempts", options.Size, options.Size, attempts)
+}
+
+// latinSquare fills a size by size grid at random so that no value
+// repeats in a row or column.
+func latinSquare(rng *rand.Rand, size int) map[base.GridKey]int {
+	grid := make(map[base.GridKey]int)
+	var fill func(i int) bool
+	fill = func(i int) bool {
+		if i == size*size {
+			return true
+		}
+		x, y := i%size+1, i/size+1
+		for _, v := range rng.Perm(size) {
+			v += 1
+			used := false
+			for j := 1; j < x && !used; j++ {
+				used = grid[base.MakeGridKey(j, y)] == v
+			}
+			for j := 1; j < y && !used; j++ {
+				used = grid[base.MakeGridKey(x, j)] == v
+			}
+			if used {
+				continue
+			}
+			grid[base.MakeGridKey(x, y)] = v
+			if fill(i + 1) {
+				return true
+			}
+		}
+		delete(grid, base.MakeGridKey(x, y))
+		return false
+	}
+	fill(0)
+	return grid
+}
+
+// neighbors returns the cells next to key, in the order up, right,
+// down, left.
+func (g *kenkenGenerator) neighbors(key base.GridKey) []base.GridKey {
+	result := []base.GridKey{}
+	for _, d := range [][2]int{{0, -1}, {1, 0}, {0, 1}, {-1, 0}} {
+		x, y := key.X+d[0], key.Y+d[1]
+		if x >= 1 && x <= g.size && y >= 1 && y <= g.size {
+			result = append(result, base.MakeGridKey(x, y))
+		}
+	}
+	return result
+}
+
+// partition splits the grid into cages.  Starting from each cell that
+// isn't in a cage yet, in a random order, a cage grows into random
+// neighboring cells until it reaches a size chosen from the weights or
+// has nowhere to grow.
+func (g *kenkenGenerator) partition(weights []int, total int) {
+	caged := make(map[base.GridKey]bool)
+	for _, i := range g.rng.Perm(g.size * g.size) {
+		start := base.MakeGridKey(i%g.size+1, i/g.size+1)
+		if caged[start] {
+			continue
+		}
+		size := 1
+		for n := g.rng.Intn(total); n >= weights[size-1]; size++ {
+			n -= weights[size-1]
+		}
+		cage := &kenkenCage{cells: []base.GridKey{start}}
+		caged[start] = true
+		for len(cage.cells) < size {
+			candidates := []base.GridKey{}
+			for _, c := range cage.cells {
+				for _, n := range g.neighbors(c) {
+					if !caged[n] {
+						candidates = append(candidates, n)
+					}
+				}
+			}
+			if len(candidates) == 0 {
+				break
+			}
+			next := candidates[g.rng.Intn(len(candidates))]
+			caged[next] = true
+			cage.cells = append(cage.cells, next)
+		}
+		g.cages = append(g.cages, cage)
+	}
+}
+
+// pickRule picks a random operator for the cage, among those that give
+// a whole positive target for its values, and sets its target.
+func (g *kenkenGenerator) pickRule(cage *kenkenCage) {
+	values := []int{}
+	for _, c := range cage.cells {
+		values = append(values, g.grid[c])
+	}
+	if len(values) == 1 {
+		cage.symbol, cage.target = "", values[0]
+		return
+	}
+	sum, product := 0, 1
+	for _, v := range values {
+		sum += v
+		product *= v
+	}
+	type rule struct {
+		symbol string
+		target int
+	}
+	rules := []rule{{"+", sum}, {"*", product}}
+	if len(values) == 2 {
+		a, b := values[0], values[1]
+		if a < b {
+			a, b = b, a
+		}
+		if a != b {
+			rules = append(rules, rule{"-", a - b})
+		}
+		if a%b == 0 && a != b {
+			rules = append(rules, rule{"/", a / b})
+		}
+	}
+	for {
+		r := rules[g.rng.Intn(len(rules))]
+		// A reworked cage changes its rule if it can.
+		if cage.symbol == r.symbol && len(rules) > 1 {
+			continue
+		}
+		cage.symbol, cage.target = r.symbol, r.target
+		return
+	}
+}
+
+// rework changes the cage of a cell that the constraints of p, the
+// puzzle of the current cages, can't solve.  The cage is given a new
+// rule the first time and is split up after that.
+func (g *kenkenGenerator) rework(p *base.Puzzle) {
+	clone := p.Clone()
+	clone.DoConstraints()
+	unsolved := []base.GridKey{}
+	for y := 1; y <= g.size; y++ {
+		for x := 1; x <= g.size; x++ {
+			if solved, _ := clone.Cell(x, y).IsSolved(); !solved {
+				unsolved = append(unsolved, base.MakeGridKey(x, y))
+			}
+		}
+	}
+	if len(unsolved) == 0 {
+		// The constraints found the solution but guessing found
+		// another, which can't happen; split a cage anyway.
+		unsolved = append(unsolved, g.cages[0].cells[0])
+	}
+	key := unsolved[g.rng.Intn(len(unsolved))]
+	for i, cage := range g.cages {
+		if !containsKey(cage.cells, key) {
+			continue
+		}
+		if !cage.reworked && len(cage.cells) > 1 {
+			cage.reworked = true
+			g.pickRule(cage)
+			return
+		}
+		rest := []base.GridKey{}
+		for _, c := range cage.cells {
+			if c != key {
+				rest = append(rest, c)
+			}
+		}
+		g.cages = append(g.cages[:i], g.cages[i+1:]...)
+		split := append([]*kenkenCage{{cells: []base.GridKey{key}}}, g.components(rest)...)
+		for _, c := range split {
+			g.pickRule(c)
+		}
+		g.cages = append(g.cages, split...)
+		return
+	}
+}
+
+// components splits the cells into cages of cells that are connected.
+func (g *kenkenGenerator) components(cells []base.GridKey) []*kenkenCage {
+	result := []*kenkenCage{}
+	seen := make(map[base.GridKey]bool)
+	for _, start := range cells {
+		if seen[start] {
+			continue
+		}
+		seen[start] = true
+		cage := &kenkenCage{cells: []base.GridKey{start}}
+		for i := 0; i < len(cage.cells); i++ {
+			for _, n := range g.neighbors(cage.cells[i]) {
+				if !seen[n] && containsKey(cells, n) {
+					seen[n] = true
+					cage.cells = append(cage.cells, n)
+				}
+			}
+		}
+		result = append(result, cage)
+	}
+	return result
+}
+
+func containsKey(keys []base.GridKey, key base.GridKey) bool {
+	for _, k := range keys {
+		if k == key {
+			return true
+		}
+	}
+	return false
+}
+
+// puzzle writes the cages in TextToKenKen's format and reads them back
+// as a Puzzle with the Latin square as its Solution.
+func (g *kenkenGenerator) puzzle() (*base.Puzzle, string, error) {
+	first := func(cage *kenkenCage) int {
+		f := g.size * g.size
+		for _, c := range cage.cells {
+			if i := (c.Y-1)*g.size + c.X - 1; i < f {
+				f = i
+			}
+		}
+		return f
+	}
+	sort.Slice(g.cages, func(i, j int) bool {
+		return first(g.cages[i]) < first(g.cages[j])
+	})
+	letters := make(map[base.GridKey]string)
+	for i, cage := range g.cages {
+		for _, c := range cage.cells {
+			letters[c] = text.CageLetters[i : i+1]
+		}
+	}
+	var b strings.Builder
+	for y := 1; y <= g.size; y++ {
+		for x := 1; x <= g.size; x++ {
+			b.WriteString(letters[base.MakeGridKey(x, y)])
+		}
+		b.WriteString("\n")
+	}
+	b.WriteString("\n")
+	for i, cage := range g.cages {
+		fmt.Fprintf(&b, "%s: %d %s\n", text.CageLetters[i:i+1], cage.target, cage.symbol)
+	}
+	p, err := text.TextToKenKen(b.String())
+	if err != nil {
+		return nil, "", err
+	}
+	p.Solution = g.grid
+	written, err := text.KenKenToText(p)
+	if err != nil {
+		return nil, "", err
+	}
+	return p, written, nil
+}
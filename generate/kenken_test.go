@@ -0,0 +1,61 @@
+package generate
+
+import "sudoku/text"
+import "strings"
+import "testing"
+
+func TestKenKen(t *testing.T) {
+	for _, size := range []int{4, 6} {
+		p, written, err := KenKen(KenKenOptions{Size: size, Seed: int64(size)})
+		if err != nil {
+			t.Fatalf("%s", err)
+		}
+		t.Logf("%s", written)
+		if got := p.CountSolutions(0); got != 1 {
+			t.Errorf("%dx%d: expected a unique solution, got %d", size, size, got)
+		}
+		cages := 0
+		for _, g := range p.Groups {
+			if isCage(g) {
+				cages += 1
+			}
+		}
+		if cages == 0 {
+			t.Errorf("%dx%d: there are no cages", size, size)
+		}
+		p2, err := text.TextToKenKen(written)
+		if err != nil {
+			t.Fatalf("Can't read back\n%s\n%s", written, err)
+		}
+		if err := p2.GuessSolve(); err != nil {
+			t.Fatalf("%s", err)
+		}
+		if mismatches, err := p2.Verify(); err != nil || len(mismatches) > 0 {
+			t.Errorf("%dx%d: the solution doesn't match: %v %v", size, size, mismatches, err)
+		}
+	}
+}
+
+func TestKenKenOptions(t *testing.T) {
+	if _, _, err := KenKen(KenKenOptions{Size: 10}); err == nil {
+		t.Errorf("Expected an error for size 10")
+	}
+	if _, _, err := KenKen(KenKenOptions{Size: 4, CageSizes: []int{0, 0}}); err == nil {
+		t.Errorf("Expected an error for no cage sizes")
+	}
+	// 81 single cell cages can't all be given a letter.
+	if _, _, err := KenKen(KenKenOptions{Size: 9, CageSizes: []int{1}}); err == nil ||
+		!strings.Contains(err.Error(), "81 cages") {
+		t.Errorf("Expected an error for too many cages, got %v", err)
+	}
+	// Only pairs, although a cell can be left alone or split off.
+	p, _, err := KenKen(KenKenOptions{Size: 4, Seed: 1, CageSizes: []int{0, 1}})
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	for _, g := range p.Groups {
+		if isCage(g) && len(g.Cells()) > 2 {
+			t.Errorf("Cage %s has %d cells", g.Label(), len(g.Cells()))
+		}
+	}
+}
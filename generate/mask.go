@@ -0,0 +1,164 @@
+package generate
+
+import "sudoku/base"
+import "fmt"
+import "math/bits"
+import "math/rand"
+
+// maskSteps is how many changes to the givens of a masked sudoku are
+// tried for each full grid.
+const maskSteps = 2000
+
+// maskLimit is the number of solutions at which counting them stops
+// while searching for a masked sudoku.
+const maskLimit = 200
+
+// checkMask returns an error if no sudoku with givens exactly on the
+// mask can have a unique solution: one with fewer than 17 givens, or
+// with two empty rows in the same band or two empty columns in the
+// same stack, whose values could be swapped.
+func checkMask(mask map[base.GridKey]bool) error {
+	count := 0
+	var rows, columns [10]int
+	for key, marked := range mask {
+		if !marked {
+			continue
+		}
+		if key.X < 1 || key.X > 9 || key.Y < 1 || key.Y > 9 {
+			return fmt.Errorf("the mask marks (%d, %d), which isn't in a sudoku", key.X, key.Y)
+		}
+		count += 1
+		rows[key.Y] += 1
+		columns[key.X] += 1
+	}
+	if count < 17 {
+		return fmt.Errorf("the mask has %d cells but a unique sudoku needs at least 17 givens", count)
+	}
+	for i := 1; i <= 9; i++ {
+		for j := i + 1; j <= 9 && (j-1)/3 == (i-1)/3; j++ {
+			if rows[i] == 0 && rows[j] == 0 {
+				return fmt.Errorf("rows %d and %d of the mask are both empty, so their values could be swapped", i, j)
+			}
+			if columns[i] == 0 && columns[j] == 0 {
+				return fmt.Errorf("columns %d and %d of the mask are both empty, so their values could be swapped", i, j)
+			}
+		}
+	}
+	return nil
+}
+
+// maskGivens looks for givens on the mask that have a unique solution,
+// starting with the values of grid.  It changes one given at a time to
+// a random value, keeping the change if the puzzle still has a
+// solution and no more solutions than before.  It returns the givens
+// and their solution, or nil if none were found in maskSteps changes.
+func maskGivens(rng *rand.Rand, grid map[base.GridKey]int, mask map[base.GridKey]bool) (map[base.GridKey]int, map[base.GridKey]int) {
+	var cells [81]int
+	keys := []int{}
+	for y := 1; y <= 9; y++ {
+		for x := 1; x <= 9; x++ {
+			if key := base.MakeGridKey(x, y); mask[key] {
+				i := (y-1)*9 + x - 1
+				cells[i] = grid[key]
+				keys = append(keys, i)
+			}
+		}
+	}
+	count, _ := countSolutions(cells, maskLimit)
+	for step := 0; count != 1 && step < maskSteps; step++ {
+		i := keys[rng.Intn(len(keys))]
+		previous := cells[i]
+		cells[i] = rng.Intn(9) + 1
+		if c, _ := countSolutions(cells, maskLimit); c > 0 && c <= count {
+			count = c
+		} else {
+			cells[i] = previous
+		}
+	}
+	if count != 1 {
+		return nil, nil
+	}
+	_, solved := countSolutions(cells, 1)
+	givens := make(map[base.GridKey]int)
+	solution := make(map[base.GridKey]int)
+	for i, v := range solved {
+		key := base.MakeGridKey(i%9+1, i/9+1)
+		solution[key] = v
+		if cells[i] != 0 {
+			givens[key] = v
+		}
+	}
+	return givens, solution
+}
+
+// countSolutions counts the solutions of a sudoku, whose cells are in
+// row major order with 0 for an empty cell, stopping at limit if it's
+// not 0.  It also returns the first solution found.  The search is
+// much faster than Puzzle.CountSolutions, which matters when searching
+// for givens on a mask means counting the solutions of thousands of
+// puzzles.
+func countSolutions(cells [81]int, limit int) (int, [81]int) {
+	var rows, columns, boxes [9]uint16
+	box := func(i int) int {
+		return i/27*3 + i%9/3
+	}
+	for i, v := range cells {
+		if v == 0 {
+			continue
+		}
+		bit := uint16(1) << uint(v-1)
+		if (rows[i/9]|columns[i%9]|boxes[box(i)])&bit != 0 {
+			return 0, cells
+		}
+		rows[i/9] |= bit
+		columns[i%9] |= bit
+		boxes[box(i)] |= bit
+	}
+	count := 0
+	var first [81]int
+	var search func() bool
+	search = func() bool {
+		// The empty cell with the fewest possible values.
+		best, fewest := -1, 10
+		var possible uint16
+		for i, v := range cells {
+			if v != 0 {
+				continue
+			}
+			values := ^(rows[i/9] | columns[i%9] | boxes[box(i)]) & 0x1ff
+			if n := bits.OnesCount16(values); n < fewest {
+				best, fewest, possible = i, n, values
+				if n <= 1 {
+					break
+				}
+			}
+		}
+		if best < 0 {
+			if count == 0 {
+				first = cells
+			}
+			count += 1
+			return limit > 0 && count >= limit
+		}
+		r, c, b := best/9, best%9, box(best)
+		for ; possible != 0; possible &= possible - 1 {
+			bit := possible & -possible
+			cells[best] = bits.TrailingZeros16(bit) + 1
+			rows[r] |= bit
+			columns[c] |= bit
+			boxes[b] |= bit
+			stop := search()
+			rows[r] &^= bit
+			columns[c] &^= bit
+			boxes[b] &^= bit
+			if stop {
+				cells[best] = 0
+				return true
+			}
+		}
+		cells[best] = 0
+		return false
+	}
+	search()
+	return count, first
+}
@@ -0,0 +1,105 @@
+package generate
+
+import "sudoku/base"
+import "strings"
+import "testing"
+
+func maskFromRows(rows ...string) map[base.GridKey]bool {
+	mask := make(map[base.GridKey]bool)
+	for y, row := range rows {
+		for x, c := range row {
+			if c == 'x' {
+				mask[base.MakeGridKey(x+1, y+1)] = true
+			}
+		}
+	}
+	return mask
+}
+
+var heart = maskFromRows(
+	"-xxx-xxx-",
+	"xx-xxx-xx",
+	"x---x---x",
+	"x-------x",
+	"-x-----x-",
+	"-xx---xx-",
+	"--xx-xx--",
+	"---xxx---",
+	"----x----")
+
+func TestMask(t *testing.T) {
+	p, err := Sudoku(Options{Seed: 1, Mask: heart})
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	g := givens(p)
+	if len(g) != len(heart) {
+		t.Errorf("Expected %d givens, got %d", len(heart), len(g))
+	}
+	for key := range g {
+		if !heart[key] {
+			t.Errorf("(%d, %d) is a given but isn't on the mask", key.X, key.Y)
+		}
+	}
+	if got := p.CountSolutions(0); got != 1 {
+		t.Fatalf("Expected a unique solution, got %d", got)
+	}
+	if err := p.GuessSolve(); err != nil {
+		t.Fatalf("%s", err)
+	}
+	if mismatches, err := p.Verify(); err != nil || len(mismatches) > 0 {
+		t.Errorf("The Solution isn't the solution: %v %v", mismatches, err)
+	}
+}
+
+func TestMaskErrors(t *testing.T) {
+	for _, test := range []struct {
+		options Options
+		issue   string
+	}{
+		{Options{Mask: maskFromRows("xxxxxxxxx")}, "at least 17"},
+		{Options{Mask: maskFromRows(
+			"xxxxxxxxx",
+			"xxxxxxxxx",
+			"xxxxxxxxx",
+			"xxxxxxxxx",
+			"xxxxxxxxx",
+			"xxxxxxxxx",
+			"xxxxxxxxx")}, "rows 8 and 9"},
+		{Options{Mask: heart, Symmetry: Rotational}, "can't be used"},
+	} {
+		_, err := Sudoku(test.options)
+		if err == nil || !strings.Contains(err.Error(), test.issue) {
+			t.Errorf("Expected an error about %q, got %v", test.issue, err)
+		}
+	}
+}
+
+func TestCountSolutions(t *testing.T) {
+	var cells [81]int
+	for i, c := range "8..........36......7..9.2...5...7.......457.....1...3...1....68..85...1..9....4.." {
+		if c != '.' {
+			cells[i] = int(c - '0')
+		}
+	}
+	count, solution := countSolutions(cells, 0)
+	if count != 1 {
+		t.Errorf("Expected 1 solution, got %d", count)
+	}
+	p := sudokuFromGivens(map[base.GridKey]int{})
+	for i, v := range solution {
+		if _, err := p.Cell(i%9+1, i/9+1).MustBe(v, base.Given, nil); err != nil {
+			t.Fatalf("%s", err)
+		}
+	}
+	if err := p.DoConstraints(); err != nil {
+		t.Errorf("The solution isn't valid: %s", err)
+	}
+	if count, _ := countSolutions([81]int{}, 5); count != 5 {
+		t.Errorf("Expected the count to stop at 5, got %d", count)
+	}
+	cells[1] = 8
+	if count, _ := countSolutions(cells, 0); count != 0 {
+		t.Errorf("Expected no solutions with a repeated value, got %d", count)
+	}
+}
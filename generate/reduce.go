@@ -0,0 +1,245 @@
+package generate
+
+import "sudoku/base"
+import "fmt"
+
+// maxMergedCage is the most cells that Reduce will merge into a cage,
+// since the constraint of a cage tries every combination of values.
+const maxMergedCage = 5
+
+// Given is a given value of a puzzle.
+type Given struct {
+	X     int
+	Y     int
+	Value int
+}
+
+// Reduction describes what Reduce removed from a puzzle.
+type Reduction struct {
+	// Givens are the redundant givens that were removed, in row
+	// major order.
+	Givens []Given
+	// Merges describe the KenKen cages that were merged, for example
+	// "a and b into 12 +".
+	Merges []string
+}
+
+// Reduce returns a minimal version of the puzzle, from which no
+// remaining given can be removed, and no two neighboring KenKen cages
+// merged into one with a sum or product rule, without losing the
+// uniqueness of the solution.  Givens are tried in row major order and
+// then cages in the order of their first cells.  It's an error for the
+// puzzle not to have a unique solution.
+func Reduce(p *base.Puzzle) (*base.Puzzle, *Reduction, error) {
+	if n := p.CountSolutions(2); n != 1 {
+		return nil, nil, fmt.Errorf("the puzzle has %s", solutionCount(n))
+	}
+	solved := p.Clone()
+	if err := solved.GuessSolve(); err != nil {
+		return nil, nil, err
+	}
+	r := &reducer{
+		puzzle:  p,
+		givens:  givens(p),
+		cages:   cages(p),
+		solved:  solved,
+		removed: &Reduction{},
+	}
+	for y := 1; y <= p.Size; y++ {
+		for x := 1; x <= p.Size; x++ {
+			key := base.MakeGridKey(x, y)
+			v, found := r.givens[key]
+			if !found {
+				continue
+			}
+			delete(r.givens, key)
+			if r.build().CountSolutions(2) == 1 {
+				r.removed.Givens = append(r.removed.Givens, Given{X: x, Y: y, Value: v})
+			} else {
+				r.givens[key] = v
+			}
+		}
+	}
+	for r.mergeCages() {
+	}
+	return r.build(), r.removed, nil
+}
+
+func solutionCount(n int) string {
+	if n == 0 {
+		return "no solution"
+	}
+	return "more than one solution"
+}
+
+// reducer holds the state of Reduce.  The puzzle itself isn't changed:
+// the givens and cages are kept apart from it and build makes a copy
+// of it with them.
+type reducer struct {
+	puzzle  *base.Puzzle
+	givens  map[base.GridKey]int
+	cages   []*cage
+	solved  *base.Puzzle
+	removed *Reduction
+}
+
+// cage is a KenKen cage of the puzzle that Reduce is building: the
+// cells that it covers and its rule.
+type cage struct {
+	label     string
+	cells     []base.GridKey
+	operators []*base.KenKenOperator
+	target    int
+}
+
+// givens returns the given value of each cell of the puzzle that has
+// one, by the cell's GridKey.  Cells that aren't given are left out.
+func givens(p *base.Puzzle) map[base.GridKey]int {
+	result := make(map[base.GridKey]int)
+	for y := 1; y <= p.Size; y++ {
+		for x := 1; x <= p.Size; x++ {
+			if given, v := p.Cell(x, y).IsGiven(); given {
+				result[base.MakeGridKey(x, y)] = v
+			}
+		}
+	}
+	return result
+}
+
+// cages returns the KenKen cages of the puzzle, in the order of its
+// Groups.
+func cages(p *base.Puzzle) []*cage {
+	result := []*cage{}
+	for _, g := range p.Groups {
+		for _, c := range g.Constraints() {
+			kc, ok := c.(*base.KenKenCageConstraint)
+			if !ok {
+				continue
+			}
+			cg := &cage{
+				label:     g.Label(),
+				operators: kc.Operators(),
+				target:    kc.Expect(),
+			}
+			for _, cell := range g.Cells() {
+				cg.cells = append(cg.cells, base.MakeGridKey(cell.X, cell.Y))
+			}
+			result = append(result, cg)
+			break
+		}
+	}
+	return result
+}
+
+// isCage returns true if the Group is a KenKen cage: one of its
+// constraints is a KenKenCageConstraint.  Reduce replaces such Groups
+// with its own cages.
+func isCage(g *base.Group) bool {
+	for _, c := range g.Constraints() {
+		if _, ok := c.(*base.KenKenCageConstraint); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// build returns a copy of the puzzle with the reducer's givens and
+// cages and with nothing deduced yet.
+func (r *reducer) build() *base.Puzzle {
+	p := r.puzzle.Clone()
+	p.Justifications = nil
+	groups := []*base.Group{}
+	for _, g := range p.Groups {
+		if !isCage(g) {
+			groups = append(groups, g)
+		}
+	}
+	p.Groups = groups
+	for y := 1; y <= p.Size; y++ {
+		for x := 1; x <= p.Size; x++ {
+			c := p.Cell(x, y)
+			c.Possibilities = p.Universe
+			cell_groups := []*base.Group{}
+			for _, g := range c.Groups {
+				if !isCage(g) {
+					cell_groups = append(cell_groups, g)
+				}
+			}
+			c.Groups = cell_groups
+		}
+	}
+	for _, cg := range r.cages {
+		g := base.NewGroup(p).SetLabel(cg.label)
+		for _, key := range cg.cells {
+			g.AddCell(p.Grid[key])
+		}
+		g.AddConstraint(base.MakeKenKenConstraint(cg.operators, cg.target))
+		p.Groups = append(p.Groups, g)
+	}
+	for y := 1; y <= p.Size; y++ {
+		for x := 1; x <= p.Size; x++ {
+			if v, found := r.givens[base.MakeGridKey(x, y)]; found {
+				p.Cell(x, y).MustBe(v, base.Given, nil)
+			}
+		}
+	}
+	return p
+}
+
+// mergeCages merges the first pair of neighboring cages that can be
+// merged into a cage with a sum or a product rule while keeping the
+// solution unique.  It returns false if there's no such pair.
+func (r *reducer) mergeCages() bool {
+	for i, a := range r.cages {
+		for j, b := range r.cages {
+			if j <= i || len(a.cells)+len(b.cells) > maxMergedCage || !touching(a, b) {
+				continue
+			}
+			cells := append(append([]base.GridKey{}, a.cells...), b.cells...)
+			sum, product := 0, 1
+			for _, key := range cells {
+				_, v := r.solved.Grid[key].IsSolved()
+				sum += v
+				product *= v
+			}
+			for _, rule := range []struct {
+				symbol string
+				target int
+			}{{"+", sum}, {"*", product}} {
+				merged := &cage{
+					label:     a.label,
+					cells:     cells,
+					operators: []*base.KenKenOperator{base.KenKenOperatorSymbols[rule.symbol]},
+					target:    rule.target,
+				}
+				cages := append([]*cage{}, r.cages[:i]...)
+				cages = append(cages, merged)
+				cages = append(cages, r.cages[i+1:j]...)
+				cages = append(cages, r.cages[j+1:]...)
+				previous := r.cages
+				r.cages = cages
+				if r.build().CountSolutions(2) == 1 {
+					r.removed.Merges = append(r.removed.Merges,
+						fmt.Sprintf("%s and %s into %d %s", a.label, b.label, rule.target, rule.symbol))
+					return true
+				}
+				r.cages = previous
+			}
+		}
+	}
+	return false
+}
+
+// touching returns true if a cell of one cage is next to a cell of the
+// other.
+func touching(a, b *cage) bool {
+	for _, ca := range a.cells {
+		for _, cb := range b.cells {
+			dx, dy := ca.X-cb.X, ca.Y-cb.Y
+			if dx*dx+dy*dy == 1 {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,86 @@
+package generate
+
+import "sudoku/base"
+import "fmt"
+import "reflect"
+import "testing"
+
+func TestReduceSudoku(t *testing.T) {
+	p, err := Sudoku(Options{Seed: 4, Givens: 40})
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	reduced, reduction, err := Reduce(p)
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	remaining := givens(reduced)
+	if len(reduction.Givens) == 0 || len(remaining)+len(reduction.Givens) != 40 {
+		t.Errorf("%d givens were removed and %d remain", len(reduction.Givens), len(remaining))
+	}
+	for _, g := range reduction.Givens {
+		if _, found := remaining[base.MakeGridKey(g.X, g.Y)]; found {
+			t.Errorf("(%d, %d) was removed but is still a given", g.X, g.Y)
+		}
+	}
+	if got := reduced.CountSolutions(0); got != 1 {
+		t.Fatalf("Expected a unique solution, got %d", got)
+	}
+	for key := range remaining {
+		r := &reducer{puzzle: reduced, givens: givens(reduced)}
+		delete(r.givens, key)
+		if r.build().CountSolutions(2) == 1 {
+			t.Errorf("The given at (%d, %d) isn't needed", key.X, key.Y)
+		}
+	}
+}
+
+func TestReduceKenKen(t *testing.T) {
+	p, _, err := KenKen(KenKenOptions{Size: 4, Seed: 2, CageSizes: []int{1}})
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	reduced, reduction, err := Reduce(p)
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	before, after := len(cages(p)), len(cages(reduced))
+	if len(reduction.Merges) == 0 || after != before-len(reduction.Merges) {
+		t.Errorf("%d cages became %d with merges %v", before, after, reduction.Merges)
+	}
+	if got := reduced.CountSolutions(0); got != 1 {
+		t.Errorf("Expected a unique solution, got %d", got)
+	}
+}
+
+func TestReduceLeavesPuzzle(t *testing.T) {
+	p, _, err := KenKen(KenKenOptions{Size: 4, Seed: 2, CageSizes: []int{1}})
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	describe := func() []string {
+		d := []string{fmt.Sprintf("%d groups", len(p.Groups))}
+		for _, g := range p.Groups {
+			d = append(d, fmt.Sprintf("group %s: %d cells", g.Label(), len(g.Cells())))
+		}
+		for _, c := range p.Cells() {
+			d = append(d, fmt.Sprintf("cell %d %d: %03o, %d groups", c.X, c.Y, c.Possibilities, len(c.Groups)))
+		}
+		return d
+	}
+	before := describe()
+	if _, reduction, err := Reduce(p); err != nil {
+		t.Fatalf("%s", err)
+	} else if len(reduction.Merges) == 0 {
+		t.Fatalf("Expected cages to be merged")
+	}
+	if after := describe(); !reflect.DeepEqual(before, after) {
+		t.Errorf("Reduce changed the puzzle:\n%v\n%v", before, after)
+	}
+}
+
+func TestReduceAmbiguous(t *testing.T) {
+	if _, _, err := Reduce(base.NewEmptySudoku()); err == nil {
+		t.Errorf("Expected an error for a puzzle without a unique solution")
+	}
+}
@@ -6,9 +6,8 @@ import "sudoku/base"
 import "strings"
 import "html/template"
 
-
 func ToTable(puzzle *base.Puzzle, glyphs map[int]rune) string {
-	s := &spec{ Puzzle: puzzle, Glyphs: glyphs}
+	s := &spec{Puzzle: puzzle, Glyphs: glyphs}
 	writer := bytes.NewBufferString("")
 	err := table_template.Execute(writer, s)
 	if err != nil {
@@ -47,7 +46,7 @@ func (s *spec) Glyph(rowIndex, columnIndex int) string {
 	if ok {
 		return string([]rune{glyph})
 	}
-	return "0123456789"[value:value+1]
+	return "0123456789"[value : value+1]
 }
 
 // BorderClass returns the value for the HTML CSS class attribute
@@ -75,7 +74,6 @@ func (s *spec) BorderClass(rowIndex, columnIndex int) string {
 	return strings.Join(classes, " ")
 }
 
-
 var table_template = template.Must(template.New("name").Parse(`
 {{with $spec := .}}
 	<table>
@@ -92,3 +90,28 @@ var table_template = template.Must(template.New("name").Parse(`
 	</table>
 {{end}}
 `))
+
+// ToMetadata renders the puzzle's Metadata as an HTML description list.
+// It returns an empty string if there is no Metadata.
+func ToMetadata(puzzle *base.Puzzle) string {
+	if len(puzzle.Metadata) == 0 {
+		return ""
+	}
+	writer := bytes.NewBufferString("")
+	err := metadata_template.Execute(writer, puzzle)
+	if err != nil {
+		panic(err)
+	}
+	return writer.String()
+}
+
+var metadata_template = template.Must(template.New("metadata").Parse(`
+{{with $puzzle := .}}
+	<dl class="metadata">
+		{{range $key := $puzzle.MetadataKeys}}
+			<dt>{{$key}}</dt>
+			<dd>{{index $puzzle.Metadata $key}}</dd>
+		{{end}}
+	</dl>
+{{end}}
+`))
@@ -1,9 +1,9 @@
 package html
 
+import "strings"
 import "testing"
 import "sudoku/text"
 
-
 var puzzle1 = `
 123------
 ---456---
@@ -14,7 +14,7 @@ var puzzle1 = `
 4--------
 -5-------
 --6------
-`  // end puzzle1
+` // end puzzle1
 
 func TestHTML1(t *testing.T) {
 	puzzle, err := text.TextToSudoku(puzzle1)
@@ -31,4 +31,18 @@ func TestHTML1(t *testing.T) {
 	t.Errorf("Look at HTML in log")
 }
 
-
+func TestMetadata(t *testing.T) {
+	puzzle, err := text.TextToSudoku("title: <Binary Fusion>\nauthor: Shye\n" + puzzle1)
+	if err != nil {
+		t.Fatalf("Error parsing puzzle1: %s", err)
+	}
+	got := ToMetadata(puzzle)
+	for _, want := range []string{"<dt>title</dt>", "<dd>&lt;Binary Fusion&gt;</dd>", "<dd>Shye</dd>"} {
+		if !strings.Contains(got, want) {
+			t.Errorf("Metadata HTML doesn't contain %q:\n%s", want, got)
+		}
+	}
+	if strings.Index(got, "title") > strings.Index(got, "author") {
+		t.Errorf("The title should come before the author:\n%s", got)
+	}
+}
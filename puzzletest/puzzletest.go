@@ -0,0 +1,30 @@
+// Package puzzletest has puzzles and helpers for the tests of the
+// packages that solve puzzles, so that they all test with the same
+// puzzles.
+package puzzletest
+
+import "sudoku/base"
+import "testing"
+
+// HardSudoku is a sudoku that needs guessing, as an 81 character line
+// in row major order where a '.' is an empty cell.
+const HardSudoku = "8..........36......7..9.2...5...7.......457.....1...3...1....68..85...1..9....4.."
+
+// HardSolution is the solution of HardSudoku.
+const HardSolution = "812753649943682175675491283154237896369845721287169534521974368438526917796318452"
+
+// SudokuFromLine returns a sudoku with the givens of an 81 character
+// line where a '.' is an empty cell.  The test fails if a given isn't
+// possible.
+func SudokuFromLine(t testing.TB, line string) *base.Puzzle {
+	p := base.NewEmptySudoku()
+	for i, ch := range line {
+		if ch == '.' {
+			continue
+		}
+		if _, err := p.Cell(i%9+1, i/9+1).MustBe(int(ch-'0'), base.Given, nil); err != nil {
+			t.Fatalf("%s", err)
+		}
+	}
+	return p
+}
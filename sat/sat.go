@@ -0,0 +1,251 @@
+// Package sat translates puzzles into the DIMACS CNF format that SAT
+// solvers read, and fills in a puzzle from the model that a solver
+// finds, so that the solvers can check this one on hard puzzles.
+package sat
+
+import "sudoku/base"
+import "bufio"
+import "fmt"
+import "strconv"
+import "strings"
+
+// Variable returns the number of the variable that is true when the
+// Cell at x, y has the value v.  The variables of the first Cell, in row
+// major order, come first, one for each value of the puzzle's Universe
+// in increasing order, then those of the next Cell and so on.
+func Variable(p *base.Puzzle, x, y, v int) int {
+	values := p.Universe.Values()
+	for i, value := range values {
+		if value == v {
+			return ((y-1)*p.Size+x-1)*len(values) + i + 1
+		}
+	}
+	return 0
+}
+
+// cnf is a formula in conjunctive normal form.
+type cnf struct {
+	variables int
+	clauses   [][]int
+}
+
+func (f *cnf) add(clause ...int) {
+	f.clauses = append(f.clauses, clause)
+}
+
+// atMostOne adds a clause for each pair of the variables that they
+// aren't both true.
+func (f *cnf) atMostOne(variables []int) {
+	for i, a := range variables {
+		for _, b := range variables[i+1:] {
+			f.add(-a, -b)
+		}
+	}
+}
+
+// tuples adds a variable for each combination of values of the Cells
+// that allowed accepts, which implies that the Cells have those values,
+// and a clause that one of them is true.  If possible isn't nil, the
+// combinations are only counted through while it accepts the values of
+// the first Cells, so that those that can't be allowed aren't all
+// tried.
+func (f *cnf) tuples(p *base.Puzzle, cells []*base.Cell, possible, allowed func([]int) bool) {
+	any := []int{}
+	values := make([]int, len(cells))
+	var next func(i int)
+	next = func(i int) {
+		if i < len(cells) {
+			for _, v := range cells[i].Possibilities.Values() {
+				values[i] = v
+				if possible == nil || possible(values[:i+1]) {
+					next(i + 1)
+				}
+			}
+			return
+		}
+		if !allowed(values) {
+			return
+		}
+		f.variables += 1
+		any = append(any, f.variables)
+		for j, c := range cells {
+			f.add(-f.variables, Variable(p, c.X, c.Y, values[j]))
+		}
+	}
+	next(0)
+	f.add(any...)
+}
+
+// ToDIMACS returns the puzzle as a DIMACS CNF formula, whose models are
+// its solutions.  Each Cell has a value that's one of its
+// Possibilities.  A Group that needs different values has at most one
+// Cell with each value, and a Group with NotElsewhereThenHereConstraint
+// has at least one.  For a KenKen or killer cage, each combination of
+// values that satisfies its rule has a variable of its own, numbered
+// after those of the Cells, and one of them must be true.  It's an
+// error for a Group to have any other Constraint.
+func ToDIMACS(p *base.Puzzle) (string, error) {
+	values := p.Universe.Values()
+	f := &cnf{variables: p.Size * p.Size * len(values)}
+	for _, c := range p.Cells() {
+		possible := []int{}
+		for _, v := range values {
+			if c.Possibilities.HasValue(v) {
+				possible = append(possible, Variable(p, c.X, c.Y, v))
+			} else {
+				f.add(-Variable(p, c.X, c.Y, v))
+			}
+		}
+		f.add(possible...)
+		f.atMostOne(possible)
+	}
+	for _, g := range p.Groups {
+		different, every := false, false
+		for _, constraint := range g.Constraints() {
+			switch c := constraint.(type) {
+			case *base.KenKenCageConstraint:
+				f.tuples(p, g.Cells(), nil, func(values []int) bool {
+					for _, o := range c.Operators() {
+						if o.Test(values, c.Expect()) {
+							return true
+						}
+					}
+					return false
+				})
+				continue
+			case *base.CageConstraint:
+				different = true
+				if c.Sum() != 0 {
+					smallest, largest := values[0], values[len(values)-1]
+					f.tuples(p, g.Cells(), func(prefix []int) bool {
+						// The values are different and the
+						// rest of the cells can still make up
+						// the sum.
+						sum := 0
+						for i, v := range prefix {
+							for _, w := range prefix[:i] {
+								if v == w {
+									return false
+								}
+							}
+							sum += v
+						}
+						left := len(g.Cells()) - len(prefix)
+						return sum+left*smallest <= c.Sum() && sum+left*largest >= c.Sum()
+					}, func(values []int) bool {
+						sum := 0
+						for _, v := range values {
+							sum += v
+						}
+						return sum == c.Sum()
+					})
+				}
+				continue
+			}
+			switch constraint.Name() {
+			case base.HereThenNotElsewhereConstraint.Name():
+				different = true
+			case base.NotElsewhereThenHereConstraint.Name():
+				every = true
+			default:
+				return "", fmt.Errorf("group %s has the constraint %s, which can't be written as CNF",
+					g.Label(), constraint.Name())
+			}
+		}
+		for _, v := range values {
+			variables := []int{}
+			for _, c := range g.Cells() {
+				variables = append(variables, Variable(p, c.X, c.Y, v))
+			}
+			if different {
+				f.atMostOne(variables)
+			}
+			if every {
+				f.add(variables...)
+			}
+		}
+	}
+	var b strings.Builder
+	if title := p.Metadata["title"]; title != "" {
+		fmt.Fprintf(&b, "c %s\n", title)
+	}
+	fmt.Fprintf(&b, "c Variable ((y - 1) * %d + x - 1) * %d + n is true when cell (x, y) has the nth value of %s.\n",
+		p.Size, len(values), p.Universe.String(" "))
+	fmt.Fprintf(&b, "p cnf %d %d\n", f.variables, len(f.clauses))
+	for _, clause := range f.clauses {
+		for _, literal := range clause {
+			fmt.Fprintf(&b, "%d ", literal)
+		}
+		b.WriteString("0\n")
+	}
+	return b.String(), nil
+}
+
+// satModel is the Constraint that justifies the values ReadModel sets.
+type satModel struct{}
+
+func (satModel) Name() string                     { return "SATModel" }
+func (satModel) DoConstraint(g *base.Group) error { return nil }
+
+// Model is the Constraint of the Justifications that ReadModel makes.
+var Model base.Constraint = satModel{}
+
+// ReadModel sets the value of each of the puzzle's Cells to the one
+// that a SAT solver's model of the puzzle's ToDIMACS formula gives it,
+// justified by Model.  The model is the solver's output: "v" lines of
+// the literals that are true, optionally with an "s SATISFIABLE" line
+// and "c" comments, or the "SAT" line and literals that MiniSat writes.
+// It's an error if the solver found the formula unsatisfiable or the
+// model doesn't give every Cell exactly one value.
+func ReadModel(p *base.Puzzle, model string) error {
+	values := p.Universe.Values()
+	true_variables := make(map[int]bool)
+	literals := 0
+	scanner := bufio.NewScanner(strings.NewReader(model))
+	scanner.Buffer(nil, len(model)+1)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		switch {
+		case line == "" || strings.HasPrefix(line, "c"):
+			continue
+		case line == "s SATISFIABLE" || line == "SAT":
+			continue
+		case line == "s UNSATISFIABLE" || line == "UNSAT":
+			return fmt.Errorf("the solver found that the puzzle has no solution")
+		case strings.HasPrefix(line, "s "):
+			return fmt.Errorf("the solver didn't find a solution: %s", line[2:])
+		}
+		for _, field := range strings.Fields(strings.TrimPrefix(line, "v")) {
+			literal, err := strconv.Atoi(field)
+			if err != nil {
+				return fmt.Errorf("%q isn't a literal of the model", field)
+			}
+			literals += 1
+			if literal > 0 {
+				true_variables[literal] = true
+			}
+		}
+	}
+	if literals == 0 {
+		return fmt.Errorf("the model is empty")
+	}
+	for _, c := range p.Cells() {
+		value := 0
+		for _, v := range values {
+			if !true_variables[Variable(p, c.X, c.Y, v)] {
+				continue
+			}
+			if value != 0 {
+				return fmt.Errorf("the model gives cell (%d, %d) both %d and %d", c.X, c.Y, value, v)
+			}
+			value = v
+		}
+		if value == 0 {
+			return fmt.Errorf("the model gives cell (%d, %d) no value", c.X, c.Y)
+		}
+		if _, err := c.MustBe(value, Model, nil); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,290 @@
+package sat
+
+import "sudoku/base"
+import "sudoku/puzzletest"
+import "sudoku/text"
+import "fmt"
+import "strconv"
+import "strings"
+import "testing"
+
+// standIn stands in for a SAT solver.  It reads a DIMACS CNF formula
+// and writes the model that the DPLL algorithm finds in the format of
+// the SAT competitions.
+func standIn(t *testing.T, formula string) string {
+	var assignment []int
+	clauses := [][]int{}
+	clause := []int{}
+	for _, line := range strings.Split(formula, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) == 0 || fields[0] == "c" {
+			continue
+		}
+		if fields[0] == "p" {
+			variables, err := strconv.Atoi(fields[2])
+			if err != nil {
+				t.Fatalf("%q: %s", line, err)
+			}
+			assignment = make([]int, variables+1)
+			continue
+		}
+		for _, field := range fields {
+			literal, err := strconv.Atoi(field)
+			if err != nil {
+				t.Fatalf("%q: %s", line, err)
+			}
+			if literal == 0 {
+				clauses = append(clauses, clause)
+				clause = []int{}
+			} else {
+				clause = append(clause, literal)
+			}
+		}
+	}
+	if !dpll(clauses, assignment) {
+		return "s UNSATISFIABLE\n"
+	}
+	var b strings.Builder
+	b.WriteString("s SATISFIABLE\n")
+	for v := 1; v < len(assignment); v++ {
+		if v%10 == 1 {
+			b.WriteString("v")
+		}
+		fmt.Fprintf(&b, " %d", v*assignment[v])
+		if v%10 == 0 {
+			b.WriteString("\n")
+		}
+	}
+	b.WriteString(" 0\n")
+	return b.String()
+}
+
+// dpll assigns 1 or -1 to each variable so that the clauses are
+// satisfied, returning false if they can't be.
+func dpll(clauses [][]int, assignment []int) bool {
+	value := func(literal int) int {
+		if literal < 0 {
+			return -assignment[-literal]
+		}
+		return assignment[literal]
+	}
+	set := func(literal, v int) {
+		if literal < 0 {
+			assignment[-literal] = -v
+		} else {
+			assignment[literal] = v
+		}
+	}
+	trail := []int{}
+	undo := func() {
+		for _, literal := range trail {
+			set(literal, 0)
+		}
+	}
+	// Unit propagation, and the shortest unsatisfied clause to guess in.
+	var shortest []int
+	for changed := true; changed; {
+		changed = false
+		shortest = nil
+		for _, clause := range clauses {
+			unassigned := []int{}
+			satisfied := false
+			for _, literal := range clause {
+				switch value(literal) {
+				case 1:
+					satisfied = true
+				case 0:
+					unassigned = append(unassigned, literal)
+				}
+			}
+			switch {
+			case satisfied:
+			case len(unassigned) == 0:
+				undo()
+				return false
+			case len(unassigned) == 1:
+				set(unassigned[0], 1)
+				trail = append(trail, unassigned[0])
+				changed = true
+			case shortest == nil || len(unassigned) < len(shortest):
+				shortest = unassigned
+			}
+		}
+	}
+	if shortest == nil {
+		// Variables in no clause can be anything.
+		for v := range assignment {
+			if assignment[v] == 0 {
+				assignment[v] = -1
+			}
+		}
+		return true
+	}
+	for _, literal := range []int{shortest[0], -shortest[0]} {
+		set(literal, 1)
+		if dpll(clauses, assignment) {
+			return true
+		}
+		set(literal, 0)
+	}
+	undo()
+	return false
+}
+
+func TestSudoku(t *testing.T) {
+	p := puzzletest.SudokuFromLine(t, puzzletest.HardSudoku)
+	formula, err := ToDIMACS(p)
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	if !strings.Contains(formula, "\np cnf 729 ") {
+		t.Errorf("The formula doesn't have 729 variables:\n%s", formula[:200])
+	}
+	if err := ReadModel(p, standIn(t, formula)); err != nil {
+		t.Fatalf("%s", err)
+	}
+	if !p.IsSolved() {
+		t.Fatalf("Not solved")
+	}
+	for i, ch := range puzzletest.HardSolution {
+		if want, got := int(ch-'0'), p.Cell(i%9+1, i/9+1).Possibilities.MustGet(0); got != want {
+			t.Errorf("Cell(%d, %d): want %d, got %d", i%9+1, i/9+1, want, got)
+		}
+	}
+	if j := p.Justifications[len(p.Justifications)-1]; j.Constraint.Name() != Model.Name() {
+		t.Errorf("Justified by %s, not %s", j.Constraint.Name(), Model.Name())
+	}
+}
+
+func TestKenKen(t *testing.T) {
+	p, err := text.TextToKenKen(`
+abccdd
+abccee
+affcgg
+5ffhg1
+iijhkk
+i1jllk
+
+a:  12 *
+b:  20 *
+c:  23 +
+d:   5 +
+e:  12 *
+f:  72 *
+g:  12 *
+h:   2 -
+i:  72 *
+j:   2 *
+k: 120 *
+l:  15 *
+`)
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	solved := p.Clone()
+	if err := solved.GuessSolve(); err != nil {
+		t.Fatalf("%s", err)
+	}
+	formula, err := ToDIMACS(p)
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	if err := ReadModel(p, standIn(t, formula)); err != nil {
+		t.Fatalf("%s", err)
+	}
+	for _, c := range p.Cells() {
+		if want, got := solved.Cell(c.X, c.Y).Possibilities, c.Possibilities; got != want {
+			t.Errorf("Cell(%d, %d): want %s, got %s", c.X, c.Y, want.String(","), got.String(","))
+		}
+	}
+}
+
+func TestKillerCage(t *testing.T) {
+	cage := func(p *base.Puzzle, sum int) {
+		g := base.NewGroup(p).SetLabel("cage")
+		for x := 2; x <= 4; x++ {
+			g.AddCell(p.Cell(x, 1))
+		}
+		g.AddConstraint(base.MakeCageConstraint(sum))
+		p.Groups = append(p.Groups, g)
+	}
+	// Only the orders of 1, 2 and 3 add up to 6 without repeating a
+	// value.
+	p := base.NewEmptySudoku()
+	cage(p, 6)
+	formula, err := ToDIMACS(p)
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	if !strings.Contains(formula, "\np cnf 735 ") {
+		t.Errorf("The formula doesn't have 6 variables for the cage:\n%s", formula[:200])
+	}
+
+	p = puzzletest.SudokuFromLine(t, puzzletest.HardSudoku)
+	cage(p, 10)
+	formula, err = ToDIMACS(p)
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	if err := ReadModel(p, standIn(t, formula)); err != nil {
+		t.Fatalf("%s", err)
+	}
+	for i, ch := range puzzletest.HardSolution {
+		if want, got := int(ch-'0'), p.Cell(i%9+1, i/9+1).Possibilities.MustGet(0); got != want {
+			t.Errorf("Cell(%d, %d): want %d, got %d", i%9+1, i/9+1, want, got)
+		}
+	}
+}
+
+func TestUnsatisfiable(t *testing.T) {
+	p := puzzletest.SudokuFromLine(t, "12345678."+"........9"+puzzletest.HardSudoku[18:])
+	formula, err := ToDIMACS(p)
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	model := standIn(t, formula)
+	if model != "s UNSATISFIABLE\n" {
+		t.Fatalf("The stand in solver found a model")
+	}
+	if err := ReadModel(p, model); err == nil {
+		t.Errorf("No error for an unsatisfiable formula")
+	}
+}
+
+func TestReadModel(t *testing.T) {
+	p := puzzletest.SudokuFromLine(t, puzzletest.HardSudoku)
+	formula, err := ToDIMACS(p)
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	model := standIn(t, formula)
+	// MiniSat writes SAT and the literals without a v.
+	minisat := "SAT\n" + strings.Replace(strings.TrimPrefix(model, "s SATISFIABLE\n"), "v", "", -1)
+	if err := ReadModel(p.Clone(), minisat); err != nil {
+		t.Errorf("MiniSat model: %s", err)
+	}
+	if err := ReadModel(p.Clone(), "s UNKNOWN\n"); err == nil {
+		t.Errorf("No error for an unknown result")
+	}
+	if err := ReadModel(p.Clone(), "s SATISFIABLE\n"); err == nil {
+		t.Errorf("No error for an empty model")
+	}
+	if err := ReadModel(p.Clone(), "v 1 2 0\n"); err == nil {
+		t.Errorf("No error for two values in a cell")
+	}
+	if err := ReadModel(p.Clone(), "v 1 x 0\n"); err == nil {
+		t.Errorf("No error for a bad literal")
+	}
+}
+
+func TestUnsupportedConstraint(t *testing.T) {
+	p := base.NewEmptySudoku()
+	g := base.NewGroup(p).SetLabel("thermometer")
+	g.AddCell(p.Cell(1, 1))
+	g.AddCell(p.Cell(2, 1))
+	g.AddConstraint(base.ThermometerConstraint)
+	p.Groups = append(p.Groups, g)
+	if _, err := ToDIMACS(p); err == nil {
+		t.Errorf("No error for a thermometer")
+	}
+}
@@ -0,0 +1,126 @@
+package text
+
+import "fmt"
+import "strings"
+import "sudoku/base"
+
+// isBoxDrawing returns true if c is one of the characters used to draw
+// the lines between the cells and boxes of a sudoku grid.
+func isBoxDrawing(c rune) bool {
+	switch c {
+	case '|', '+', '=', '!', ':':
+		return true
+	}
+	// The Unicode Box Drawing block.
+	return c >= 0x2500 && c <= 0x257F
+}
+
+// TolerantTextToSudoku returns an unsolved puzzle for a sudoku drawn in
+// any of the styles commonly seen in forums and email, for example
+//
+//	+-------+-------+-------+
+//	| 5 . . | . 2 . | . . 7 |
+//
+// or the same grid drawn with Unicode box drawing characters.  Lines
+// and characters that draw borders are ignored.  A digit is the value
+// of a cell and a '.', '·', '0' or '-' is an empty cell.  Spaces are
+// ignored and # starts a comment.  It is an error for a row to have
+// other than nine cells or for there to be other than nine rows.
+// A line made up of only '-' characters is a border unless it has
+// exactly nine of them, in which case it is a row of empty cells, as
+// read by TextToSudoku.
+func TolerantTextToSudoku(text string) (*base.Puzzle, error) {
+	solution, text, err := ReadSolution(text)
+	if err != nil {
+		return nil, err
+	}
+	metadata, text := ReadMetadata(text)
+	p := base.NewEmptySudoku()
+	p.Metadata = metadata
+	row := 0
+	for index, line := range strings.Split(text, "\n") {
+		linenumber := index + 1
+		if i := strings.IndexRune(line, '#'); i >= 0 {
+			line = line[:i]
+		}
+		if isBorderLine(line) {
+			continue
+		}
+		row += 1
+		column := 0
+		linecharnumber := 0
+		for _, c := range line {
+			linecharnumber += 1
+			switch {
+			case c == ' ', c == '\t', c == '\r', isBoxDrawing(c):
+				continue
+			case c == '.', c == '·', c == '0', c == '-':
+				column += 1
+			case c >= '1' && c <= '9':
+				column += 1
+				if row <= 9 && column <= 9 {
+					if err := setGiven(p.Cell(column, row), int(c-'0')); err != nil {
+						return p, fmt.Errorf("line %d, column %d: %s", linenumber, linecharnumber, err)
+					}
+				}
+			default:
+				return p, fmt.Errorf("line %d, column %d: can't read the character %q",
+					linenumber, linecharnumber, c)
+			}
+		}
+		if row > 9 {
+			return p, fmt.Errorf("line %d: there are more than 9 rows", linenumber)
+		}
+		if column != 9 {
+			return p, fmt.Errorf("line %d: row %d has %d cells rather than 9",
+				linenumber, row, column)
+		}
+	}
+	if row != 9 {
+		return p, fmt.Errorf("there are %d rows rather than 9", row)
+	}
+	return p, setSolution(p, solution)
+}
+
+// setGiven makes v the given value of the Cell.  It's an error for
+// another Cell of one of its Groups to be given v already.
+func setGiven(c *base.Cell, v int) error {
+	for _, g := range c.Groups {
+		for _, other := range g.Cells() {
+			if given, value := other.IsGiven(); given && value == v && other != c {
+				return fmt.Errorf("%d is already given at (%d, %d) in %s", v, other.X, other.Y, g.Label())
+			}
+		}
+	}
+	_, err := c.MustBe(v, base.Given, nil)
+	return err
+}
+
+// isVerticalBar returns true if c draws a vertical line.
+func isVerticalBar(c rune) bool {
+	switch c {
+	case '|', '!', ':', '│', '┃', '║', '┆', '┇', '┊', '┋':
+		return true
+	}
+	return false
+}
+
+// isBorderLine returns true if line has no cells, either because it is
+// empty or because it only draws borders.
+func isBorderLine(line string) bool {
+	dashes := 0
+	border := false
+	for _, c := range line {
+		switch {
+		case c == ' ', c == '\t', c == '\r', isVerticalBar(c):
+			continue
+		case c == '-':
+			dashes += 1
+		case isBoxDrawing(c):
+			border = true
+		default:
+			return false
+		}
+	}
+	return border || dashes != 9
+}
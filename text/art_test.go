@@ -0,0 +1,91 @@
+package text
+
+import "strings"
+import "testing"
+
+func TestTolerantTextToSudoku(t *testing.T) {
+	expect, err := TextToSudoku(`
+		2-----459
+		----7--3-
+		6-59---1-
+		3--89---1
+		--2---9--
+		1---27--5
+		-1---93-4
+		-2--3----
+		583-----2
+	`)
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	for name, art := range map[string]string{
+		"ascii": `
+			+-------+-------+-------+
+			| 2 . . | . . . | 4 5 9 |
+			| . . . | . 7 . | . 3 . |
+			| 6 . 5 | 9 . . | . 1 . |
+			+-------+-------+-------+
+			| 3 . . | 8 9 . | . . 1 |
+			| . . 2 | . . . | 9 . . |
+			| 1 . . | . 2 7 | . . 5 |
+			+-------+-------+-------+
+			| . 1 . | . . 9 | 3 . 4 |
+			| . 2 . | . 3 . | . . . |
+			| 5 8 3 | . . . | . . 2 |
+			+-------+-------+-------+
+		`,
+		"zeros": `
+			200 000 459
+			000 070 030
+			605 900 010
+			------------
+			300 890 001
+			002 000 900
+			100 027 005
+			------------
+			010 009 304
+			020 030 000
+			583 000 002
+		`,
+		"unicode": `
+			╔═══════╤═══════╤═══════╗
+			║ 2 · · │ · · · │ 4 5 9 ║
+			║ - - - │ - 7 - │ - 3 - ║
+			║ 6 - 5 │ 9 - - │ - 1 - ║
+			╟───────┼───────┼───────╢
+			║ 3 - - │ 8 9 - │ - - 1 ║
+			║ - - 2 │ - - - │ 9 - - ║
+			║ 1 - - │ - 2 7 │ - - 5 ║
+			╟───────┼───────┼───────╢
+			║ - 1 - │ - - 9 │ 3 - 4 ║
+			║ - 2 - │ - 3 - │ - - - ║
+			║ 5 8 3 │ - - - │ - - 2 ║
+			╚═══════╧═══════╧═══════╝
+		`,
+	} {
+		p, err := TolerantTextToSudoku(art)
+		if err != nil {
+			t.Errorf("%s: %s", name, err)
+			continue
+		}
+		checkSamePuzzle(t, name, expect, p)
+	}
+}
+
+func TestTolerantTextToSudokuErrors(t *testing.T) {
+	for _, test := range []struct {
+		text   string
+		expect string
+	}{
+		{"123456789\n45678912\n", "line 2: row 2 has 8 cells rather than 9"},
+		{"123 456 789\n", "there are 1 rows rather than 9"},
+		{"| 1 2 3 | x 5 6 |", "line 1, column 11: can't read the character 'x'"},
+		{strings.Repeat("---------\n", 10), "line 10: there are more than 9 rows"},
+		{"| 1 2 3 | 4 5 6 | 7 8 1 |", "line 1, column 23: 1 is already given at (1, 1) in row1"},
+	} {
+		_, err := TolerantTextToSudoku(test.text)
+		if err == nil || err.Error() != test.expect {
+			t.Errorf("Wrong error for %q: want %q, got %v", test.text, test.expect, err)
+		}
+	}
+}
@@ -0,0 +1,416 @@
+package text
+
+// Import of the puzzles that f-puzzles.com and the setters who use it
+// share as lz-string compressed, base64 encoded JSON.
+
+import "encoding/json"
+import "fmt"
+import "net/url"
+import "sort"
+import "strconv"
+import "strings"
+import "sudoku/base"
+
+// UnsupportedConstraints lists the constraints of an f-puzzles puzzle
+// that FPuzzlesToPuzzle couldn't represent.  The Puzzle it's returned
+// with has all of the other constraints.
+type UnsupportedConstraints []string
+
+func (u UnsupportedConstraints) Error() string {
+	return "unsupported f-puzzles constraints: " + strings.Join(u, ", ")
+}
+
+type fpuzzlesCell struct {
+	Value  int  `json:"value"`
+	Given  bool `json:"given"`
+	Region *int `json:"region"`
+}
+
+// fpuzzlesCells is a killer cage or extra region.
+type fpuzzlesCells struct {
+	Cells []string        `json:"cells"`
+	Value json.RawMessage `json:"value"`
+}
+
+// fpuzzlesLines is a thermometer or arrow.  For an arrow, Cells is its
+// circle and each line starts in the circle.
+type fpuzzlesLines struct {
+	Lines [][]string `json:"lines"`
+	Cells []string   `json:"cells"`
+}
+
+type fpuzzles struct {
+	Size             int              `json:"size"`
+	Title            string           `json:"title"`
+	Author           string           `json:"author"`
+	Ruleset          string           `json:"ruleset"`
+	Grid             [][]fpuzzlesCell `json:"grid"`
+	DiagonalPositive bool             `json:"diagonal+"`
+	DiagonalNegative bool             `json:"diagonal-"`
+	KillerCage       []fpuzzlesCells  `json:"killercage"`
+	ExtraRegion      []fpuzzlesCells  `json:"extraregion"`
+	Thermometer      []fpuzzlesLines  `json:"thermometer"`
+	Arrow            []fpuzzlesLines  `json:"arrow"`
+}
+
+// fpuzzlesIgnored are the f-puzzles fields that don't affect the
+// solution of the puzzle, including the cosmetic text, lines and
+// shapes that setters draw on the grid.
+var fpuzzlesIgnored = map[string]bool{
+	"title":                 true,
+	"author":                true,
+	"ruleset":               true,
+	"solution":              true,
+	"highlightConflicts":    true,
+	"disabledlogic":         true,
+	"truecandidatesoptions": true,
+	"text":                  true,
+	"line":                  true,
+	"rectangle":             true,
+	"circle":                true,
+}
+
+// fpuzzlesCosmeticCages returns true if the f-puzzles cosmetic cages
+// have no values.  A cage with a value is a rule that's only written
+// in the cage.
+func fpuzzlesCosmeticCages(value json.RawMessage) bool {
+	cages := []fpuzzlesCells{}
+	if err := json.Unmarshal(value, &cages); err != nil {
+		return false
+	}
+	for _, c := range cages {
+		switch strings.TrimSpace(string(c.Value)) {
+		case "", "null", `""`:
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// fpuzzlesSupported are the f-puzzles fields that FPuzzlesToPuzzle
+// reads.
+var fpuzzlesSupported = map[string]bool{
+	"size":        true,
+	"grid":        true,
+	"diagonal+":   true,
+	"diagonal-":   true,
+	"killercage":  true,
+	"extraregion": true,
+	"thermometer": true,
+	"arrow":       true,
+}
+
+// FPuzzlesJSON returns the JSON description of an f-puzzles puzzle.
+// data can be the JSON itself, the compressed and base64 encoded JSON,
+// or an f-puzzles URL with that as its load parameter.
+func FPuzzlesJSON(data string) (string, error) {
+	data = strings.TrimSpace(data)
+	if strings.HasPrefix(data, "{") {
+		return data, nil
+	}
+	if i := strings.Index(data, "load="); i >= 0 {
+		data = data[i+len("load="):]
+		if j := strings.IndexAny(data, "&#"); j >= 0 {
+			data = data[:j]
+		}
+		unescaped, err := url.PathUnescape(data)
+		if err != nil {
+			return "", err
+		}
+		data = unescaped
+	}
+	// Spaces can replace the +s of a URL that's been unescaped once too
+	// often.
+	data = strings.Replace(data, " ", "+", -1)
+	decompressed, err := lzDecompressFromBase64(data)
+	if err != nil {
+		return "", err
+	}
+	if !strings.HasPrefix(decompressed, "{") {
+		return "", fmt.Errorf("the f-puzzles data didn't decompress to JSON")
+	}
+	return decompressed, nil
+}
+
+// FPuzzlesToPuzzle returns an unsolved puzzle for the f-puzzles data,
+// in any of the forms that FPuzzlesJSON accepts.  The regions of the
+// grid, the givens, the diagonals, killer cages, thermometers, arrows
+// and extra regions are supported.  The title, author and rules become
+// the Puzzle's Metadata.  If the puzzle has any other constraints, the
+// Puzzle is returned along with an UnsupportedConstraints error that
+// names them.
+func FPuzzlesToPuzzle(data string) (*base.Puzzle, error) {
+	text, err := FPuzzlesJSON(data)
+	if err != nil {
+		return nil, err
+	}
+	fields := make(map[string]json.RawMessage)
+	if err := json.Unmarshal([]byte(text), &fields); err != nil {
+		return nil, fmt.Errorf("can't read f-puzzles JSON: %s", err)
+	}
+	var fp fpuzzles
+	if err := json.Unmarshal([]byte(text), &fp); err != nil {
+		return nil, fmt.Errorf("can't read f-puzzles JSON: %s", err)
+	}
+	if fp.Size < 1 || fp.Size > base.MaxValue {
+		return nil, fmt.Errorf("f-puzzles grids of size %d aren't supported", fp.Size)
+	}
+	if len(fp.Grid) != fp.Size {
+		return nil, fmt.Errorf("the f-puzzles grid has %d rows rather than %d", len(fp.Grid), fp.Size)
+	}
+	for i, row := range fp.Grid {
+		if len(row) != fp.Size {
+			return nil, fmt.Errorf("row %d of the f-puzzles grid has %d cells rather than %d",
+				i+1, len(row), fp.Size)
+		}
+	}
+
+	p, err := fpuzzlesGrid(&fp)
+	if err != nil {
+		return nil, err
+	}
+	for key, value := range map[string]string{
+		"title":  fp.Title,
+		"author": fp.Author,
+		"rules":  fp.Ruleset,
+	} {
+		if value != "" {
+			p.SetMetadata(key, value)
+		}
+	}
+	cell := func(name string) (*base.Cell, error) {
+		var row, column int
+		if n, _ := fmt.Sscanf(strings.ToUpper(name), "R%dC%d", &row, &column); n != 2 ||
+			row < 1 || row > p.Size || column < 1 || column > p.Size {
+			return nil, fmt.Errorf("invalid f-puzzles cell %q", name)
+		}
+		return p.Cell(column, row), nil
+	}
+	group := func(label string, names []string, constraint base.Constraint) (*base.Group, error) {
+		g := base.NewGroup(p).SetLabel(label)
+		for _, name := range names {
+			c, err := cell(name)
+			if err != nil {
+				return nil, err
+			}
+			g.AddCell(c)
+		}
+		if len(g.Cells()) == 0 {
+			return nil, fmt.Errorf("%s has no cells", label)
+		}
+		g.AddConstraint(constraint)
+		return g, nil
+	}
+	groups := []*base.Group{}
+	add := func(g *base.Group, err error) error {
+		if err == nil {
+			groups = append(groups, g)
+		}
+		return err
+	}
+
+	if fp.DiagonalPositive {
+		names := []string{}
+		for i := 1; i <= p.Size; i++ {
+			names = append(names, fmt.Sprintf("R%dC%d", p.Size+1-i, i))
+		}
+		if err := add(group("diagonal+", names, base.HereThenNotElsewhereConstraint)); err != nil {
+			return nil, err
+		}
+		groups[len(groups)-1].AddConstraint(base.NotElsewhereThenHereConstraint)
+	}
+	if fp.DiagonalNegative {
+		names := []string{}
+		for i := 1; i <= p.Size; i++ {
+			names = append(names, fmt.Sprintf("R%dC%d", i, i))
+		}
+		if err := add(group("diagonal-", names, base.HereThenNotElsewhereConstraint)); err != nil {
+			return nil, err
+		}
+		groups[len(groups)-1].AddConstraint(base.NotElsewhereThenHereConstraint)
+	}
+	for i, cage := range fp.KillerCage {
+		sum, err := fpuzzlesNumber(cage.Value)
+		if err != nil {
+			return nil, fmt.Errorf("killer cage %d: %s", i+1, err)
+		}
+		if err := add(group(fmt.Sprintf("cage%d", i+1), cage.Cells, base.MakeCageConstraint(sum))); err != nil {
+			return nil, err
+		}
+	}
+	for i, region := range fp.ExtraRegion {
+		label := fmt.Sprintf("extraregion%d", i+1)
+		if len(region.Cells) == p.Size {
+			if err := add(group(label, region.Cells, base.HereThenNotElsewhereConstraint)); err != nil {
+				return nil, err
+			}
+			groups[len(groups)-1].AddConstraint(base.NotElsewhereThenHereConstraint)
+		} else if err := add(group(label, region.Cells, base.MakeCageConstraint(0))); err != nil {
+			return nil, err
+		}
+	}
+	count := 0
+	for _, thermometer := range fp.Thermometer {
+		for _, line := range thermometer.Lines {
+			count += 1
+			if err := add(group(fmt.Sprintf("thermometer%d", count), line, base.ThermometerConstraint)); err != nil {
+				return nil, err
+			}
+		}
+	}
+	for i, arrow := range fp.Arrow {
+		circle, shaft, err := fpuzzlesArrow(arrow)
+		if err != nil {
+			return nil, fmt.Errorf("arrow %d: %s", i+1, err)
+		}
+		if err := add(group(fmt.Sprintf("arrow%d", i+1), append(circle, shaft...),
+			base.MakeArrowConstraint(len(circle)))); err != nil {
+			return nil, err
+		}
+	}
+	for _, g := range groups {
+		p.AddGroup(g)
+	}
+
+	for y, row := range fp.Grid {
+		for x, c := range row {
+			if !c.Given || c.Value == 0 {
+				continue
+			}
+			if c.Value < 0 || c.Value > p.Size {
+				return nil, fmt.Errorf("the given at R%dC%d is out of range: %d", y+1, x+1, c.Value)
+			}
+			if _, err := p.Cell(x+1, y+1).MustBe(c.Value, base.Given, nil); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	unsupported := UnsupportedConstraints{}
+	for name, value := range fields {
+		if fpuzzlesSupported[name] || fpuzzlesIgnored[name] {
+			continue
+		}
+		if name == "cage" && fpuzzlesCosmeticCages(value) {
+			continue
+		}
+		switch strings.TrimSpace(string(value)) {
+		case "", "null", "false", "[]", "{}", `""`:
+			continue
+		}
+		unsupported = append(unsupported, name)
+	}
+	if len(unsupported) > 0 {
+		sort.Strings(unsupported)
+		return p, unsupported
+	}
+	return p, nil
+}
+
+// fpuzzlesGrid returns a Puzzle with the rows, columns and regions of
+// the grid.  Regions are the usual boxes unless cells of the grid say
+// otherwise.
+func fpuzzlesGrid(fp *fpuzzles) (*base.Puzzle, error) {
+	height := 1
+	for h := 1; h*h <= fp.Size; h++ {
+		if fp.Size%h == 0 {
+			height = h
+		}
+	}
+	width := fp.Size / height
+	regular := true
+	regions := make(map[int][]base.GridKey)
+	for y, row := range fp.Grid {
+		for x, c := range row {
+			region := (y/height)*height + x/width
+			if height == 1 {
+				// There are no boxes for a prime size.
+				region = -1
+			}
+			if c.Region != nil {
+				regular = false
+				region = *c.Region
+			}
+			if region >= 0 {
+				regions[region] = append(regions[region], base.MakeGridKey(x+1, y+1))
+			}
+		}
+	}
+	if fp.Size == 9 && regular {
+		return base.NewEmptySudoku(), nil
+	}
+
+	p := &base.Puzzle{}
+	p.MakeCells(fp.Size)
+	p.AddLineGroups()
+	numbers := []int{}
+	for region := range regions {
+		numbers = append(numbers, region)
+	}
+	sort.Ints(numbers)
+	for _, region := range numbers {
+		if len(regions[region]) != fp.Size {
+			return nil, fmt.Errorf("region %d has %d cells rather than %d",
+				region+1, len(regions[region]), fp.Size)
+		}
+		g := base.NewGroup(p).SetLabel(fmt.Sprintf("region%d", region+1))
+		for _, key := range regions[region] {
+			g.AddCell(p.Cell(key.X, key.Y))
+		}
+		g.AddConstraint(base.HereThenNotElsewhereConstraint)
+		g.AddConstraint(base.NotElsewhereThenHereConstraint)
+		p.AddGroup(g)
+	}
+	return p, nil
+}
+
+// fpuzzlesNumber reads a clue, which f-puzzles writes as either a
+// string or a number.  A missing clue is 0.
+func fpuzzlesNumber(value json.RawMessage) (int, error) {
+	s := strings.Trim(strings.TrimSpace(string(value)), `"`)
+	if s == "" || s == "null" {
+		return 0, nil
+	}
+	n, err := strconv.Atoi(s)
+	if err != nil {
+		return 0, fmt.Errorf("can't read the clue %s", value)
+	}
+	return n, nil
+}
+
+// fpuzzlesArrow returns the cells of an arrow's circle, in reading
+// order, and the cells of its shaft.
+func fpuzzlesArrow(arrow fpuzzlesLines) ([]string, []string, error) {
+	if len(arrow.Cells) == 0 {
+		return nil, nil, fmt.Errorf("the arrow has no circle")
+	}
+	position := func(name string) (int, int) {
+		var row, column int
+		fmt.Sscanf(strings.ToUpper(name), "R%dC%d", &row, &column)
+		return row, column
+	}
+	circle := append([]string{}, arrow.Cells...)
+	sort.SliceStable(circle, func(i, j int) bool {
+		ri, ci := position(circle[i])
+		rj, cj := position(circle[j])
+		return ri < rj || (ri == rj && ci < cj)
+	})
+	seen := make(map[string]bool)
+	for _, name := range circle {
+		seen[strings.ToUpper(name)] = true
+	}
+	shaft := []string{}
+	for _, line := range arrow.Lines {
+		for _, name := range line {
+			if !seen[strings.ToUpper(name)] {
+				seen[strings.ToUpper(name)] = true
+				shaft = append(shaft, name)
+			}
+		}
+	}
+	if len(shaft) == 0 {
+		return nil, nil, fmt.Errorf("the arrow has no shaft")
+	}
+	return circle, shaft, nil
+}
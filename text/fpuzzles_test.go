@@ -0,0 +1,304 @@
+package text
+
+import "fmt"
+import "io/ioutil"
+import "strings"
+import "testing"
+import "sudoku/base"
+
+func TestLZString(t *testing.T) {
+	for compressed, want := range map[string]string{
+		"BIUwNmD2A0AEDukBOYAmQ===": "Hello, world",
+		"Q===":                     "",
+	} {
+		got, err := lzDecompressFromBase64(compressed)
+		if err != nil {
+			t.Errorf("%s: %s", compressed, err)
+			continue
+		}
+		if got != want {
+			t.Errorf("%s: want %q, got %q", compressed, want, got)
+		}
+	}
+	for _, bad := range []string{"BIUwNmD2A0AEDuk", "BIU*NmD2A0AEDukBOYAmQ==="} {
+		if _, err := lzDecompressFromBase64(bad); err == nil {
+			t.Errorf("Expected an error for %q", bad)
+		}
+	}
+}
+
+func TestFPuzzlesBinaryFusion(t *testing.T) {
+	bytes, err := ioutil.ReadFile("../text_application/examples/shye_Binary_Fusion.fpuzzles")
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	url := string(bytes)
+	p, err := FPuzzlesToPuzzle(url)
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	bytes, err = ioutil.ReadFile("../text_application/examples/shye_Binary_Fusion.txt")
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	expect, err := TextToSudoku(string(bytes))
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	checkSamePuzzle(t, "Binary Fusion", expect, p)
+
+	// Without the URL.
+	compressed := strings.TrimSpace(url[strings.Index(url, "load=")+len("load="):])
+	if _, err := FPuzzlesToPuzzle(compressed); err != nil {
+		t.Errorf("%s", err)
+	}
+}
+
+func TestFPuzzlesDiagonalArrows(t *testing.T) {
+	bytes, err := ioutil.ReadFile("../text_application/examples/diagonal_arrows.fpuzzles")
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	p, err := FPuzzlesToPuzzle(string(bytes))
+	unsupported, ok := err.(UnsupportedConstraints)
+	if !ok || strings.Join(unsupported, ",") != "sandwichsum" {
+		t.Fatalf("Expected an unsupported sandwichsum, got %v", err)
+	}
+	if title := p.Metadata["title"]; title != "Diagonal Arrows" {
+		t.Errorf("Wrong title: %q", title)
+	}
+	groups := make(map[string]string)
+	for _, g := range p.Groups {
+		names := []string{}
+		for _, c := range g.Constraints() {
+			names = append(names, c.Name())
+		}
+		cells := []string{}
+		for _, c := range g.Cells() {
+			cells = append(cells, fmt.Sprintf("R%dC%d", c.Y, c.X))
+		}
+		groups[g.Label()] = strings.Join(names, ",") + ": " + strings.Join(cells, " ")
+	}
+	unique := "HereThenNotElsewhereConstraint,NotElsewhereThenHereConstraint: "
+	for label, want := range map[string]string{
+		"diagonal+":    unique + "R9C1 R8C2 R7C3 R6C4 R5C5 R4C6 R3C7 R2C8 R1C9",
+		"diagonal-":    unique + "R1C1 R2C2 R3C3 R4C4 R5C5 R6C6 R7C7 R8C8 R9C9",
+		"cage1":        "Cage = 19: R5C1 R6C1 R6C2",
+		"cage2":        "Cage: R1C4 R1C5",
+		"extraregion1": "Cage: R2C2 R2C8 R8C2 R8C8",
+		"thermometer1": "ThermometerConstraint: R4C3 R4C4 R4C5 R4C6 R3C6",
+		"arrow1":       "Arrow: R1C8 R2C8 R3C7",
+		"arrow2":       "Arrow: R9C1 R9C2 R8C2 R7C3",
+	} {
+		if got := groups[label]; got != want {
+			t.Errorf("Group %s: want %q, got %q", label, want, got)
+		}
+	}
+	// The 9 rows, columns and boxes and the 8 groups above.
+	if len(p.Groups) != 35 {
+		t.Errorf("Expected 35 groups, got %d", len(p.Groups))
+	}
+	if err := p.GuessSolve(); err != nil {
+		t.Fatalf("%s", err)
+	}
+	solution := "524617893678539142319248567841356279756924318932871456287495631493162785165783924"
+	for y := 1; y <= 9; y++ {
+		for x := 1; x <= 9; x++ {
+			want := int(solution[(y-1)*9+x-1] - '0')
+			if _, v := p.Cell(x, y).IsSolved(); v != want {
+				t.Errorf("Cell(%d, %d): want %d, got %d", x, y, want, v)
+			}
+		}
+	}
+}
+
+// fpuzzlesVariant is the sudoku of sudoku_1.txt with constraints that
+// agree with its solution:
+//
+//	2 7 1 6 8 3 4 5 9
+//	4 9 8 5 7 1 2 3 6
+//	6 3 5 9 4 2 7 1 8
+//	3 4 7 8 9 5 6 2 1
+//	8 5 2 4 1 6 9 7 3
+//	1 6 9 3 2 7 8 4 5
+//	7 1 6 2 5 9 3 8 4
+//	9 2 4 1 3 8 5 6 7
+//	5 8 3 7 6 4 1 9 2
+const fpuzzlesVariant = `{
+	"size": 9,
+	"title": "Variant",
+	"grid": [
+			[{"value":2,"given":true},{},{},{},{},{},{"value":4,"given":true},{"value":5,"given":true},{"value":9,"given":true}],
+			[{},{},{},{},{"value":7,"given":true},{},{},{"value":3,"given":true},{}],
+			[{"value":6,"given":true},{},{"value":5,"given":true},{"value":9,"given":true},{},{},{},{"value":1,"given":true},{}],
+			[{"value":3,"given":true},{},{},{"value":8,"given":true},{"value":9,"given":true},{},{},{},{"value":1,"given":true}],
+			[{},{},{"value":2,"given":true},{},{},{},{"value":9,"given":true},{},{}],
+			[{"value":1,"given":true},{},{},{},{"value":2,"given":true},{"value":7,"given":true},{},{},{"value":5,"given":true}],
+			[{},{"value":1,"given":true},{},{},{},{"value":9,"given":true},{"value":3,"given":true},{},{"value":4,"given":true}],
+			[{},{"value":2,"given":true},{},{},{"value":3,"given":true},{},{},{},{}],
+			[{"value":5,"given":true},{"value":8,"given":true},{"value":3,"given":true},{},{},{},{},{},{"value":2,"given":true}]
+	],
+	"thermometer": [{"lines": [["R1C3", "R1C4", "R1C5"]]}],
+	"arrow": [
+		{"lines": [["R6C3", "R5C2", "R4C2"]], "cells": ["R6C3"]},
+		{"lines": [["R1C2", "R2C2", "R3C2", "R4C2", "R5C2", "R6C2"]], "cells": ["R1C2", "R1C1"]}
+	],
+	"killercage": [
+		{"cells": ["R7C1", "R8C1", "R8C2"], "value": "18"},
+		{"cells": ["R9C4", "R9C5"]}
+	],
+	"extraregion": [{"cells": ["R2C2", "R2C8", "R8C2", "R8C8"]}],
+	"antiknight": false,
+	"odd": [],
+	"nonconsecutive": true,
+	"sandwichsum": [{"cell": "R0C1", "value": "5"}]
+}`
+
+func TestFPuzzlesVariant(t *testing.T) {
+	p, err := FPuzzlesToPuzzle(fpuzzlesVariant)
+	unsupported, ok := err.(UnsupportedConstraints)
+	if !ok {
+		t.Fatalf("Expected UnsupportedConstraints, got %v", err)
+	}
+	if got := strings.Join(unsupported, ","); got != "nonconsecutive,sandwichsum" {
+		t.Errorf("Wrong unsupported constraints: %s", got)
+	}
+	constraints := make(map[string]string)
+	for _, g := range p.Groups {
+		names := []string{}
+		for _, c := range g.Constraints() {
+			names = append(names, c.Name())
+		}
+		constraints[g.Label()] = strings.Join(names, ",")
+	}
+	for label, want := range map[string]string{
+		"thermometer1": "ThermometerConstraint",
+		"arrow1":       "Arrow",
+		"arrow2":       "Arrow",
+		"cage1":        "Cage = 18",
+		"cage2":        "Cage",
+		"extraregion1": "Cage",
+	} {
+		if got := constraints[label]; got != want {
+			t.Errorf("Group %s: want %q, got %q", label, want, got)
+		}
+	}
+	for _, g := range p.Groups {
+		if g.Label() == "arrow2" {
+			if c := g.Cells()[0]; c.X != 1 || c.Y != 1 {
+				t.Errorf("The circle of arrow2 should start at R1C1")
+			}
+			if n := len(g.Cells()); n != 7 {
+				t.Errorf("arrow2 should have 7 cells, not %d", n)
+			}
+		}
+	}
+	if err := p.DoConstraints(); err != nil {
+		t.Fatalf("Error during DoConstraints: %s", err.Error())
+	}
+	solution := "271683459498571236635942718347895621852416973169327845716259384924138567583764192"
+	for y := 1; y <= 9; y++ {
+		for x := 1; x <= 9; x++ {
+			want := int(solution[(y-1)*9+x-1] - '0')
+			if solved, v := p.Cell(x, y).IsSolved(); !solved || v != want {
+				t.Errorf("Cell(%d, %d): want %d, got %s", x, y, want,
+					p.Cell(x, y).Possibilities.String(","))
+			}
+		}
+	}
+}
+
+func TestFPuzzlesRegions(t *testing.T) {
+	p, err := FPuzzlesToPuzzle(`{"size": 4, "grid": [
+		[{"value": 1, "given": true}, {}, {}, {}],
+		[{}, {}, {}, {}],
+		[{}, {}, {}, {}],
+		[{}, {}, {}, {}]
+	]}`)
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	regions := 0
+	for _, g := range p.Groups {
+		if strings.HasPrefix(g.Label(), "region") {
+			regions += 1
+			if g.Label() == "region1" && !g.HasCell(p.Cell(2, 2)) {
+				t.Errorf("region1 should be the top left 2 by 2 box")
+			}
+		}
+	}
+	if regions != 4 {
+		t.Errorf("Expected 4 regions, got %d", regions)
+	}
+	if err := p.DoConstraints(); err != nil {
+		t.Fatalf("Error during DoConstraints: %s", err.Error())
+	}
+	if p.Cell(2, 2).HasPossibleValue(1) {
+		t.Errorf("The given should be eliminated from its region")
+	}
+
+	// A grid can say which region each cell is in.
+	grid := []string{}
+	for y := 0; y < 4; y++ {
+		row := []string{}
+		for x := 0; x < 4; x++ {
+			row = append(row, fmt.Sprintf(`{"region": %d}`, y))
+		}
+		grid = append(grid, "["+strings.Join(row, ",")+"]")
+	}
+	p, err = FPuzzlesToPuzzle(`{"size": 4, "grid": [` + strings.Join(grid, ",") + `]}`)
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	var region *base.Group
+	for _, g := range p.Groups {
+		if g.Label() == "region2" {
+			region = g
+		}
+	}
+	if region == nil || !region.HasCell(p.Cell(4, 2)) {
+		t.Errorf("region2 should be the second row")
+	}
+}
+
+func TestFPuzzlesCosmetic(t *testing.T) {
+	grid := `"grid": [[{},{},{},{}],[{},{},{},{}],[{},{},{},{}],[{},{},{},{}]]`
+	p, err := FPuzzlesToPuzzle(`{"size": 4, ` + grid + `,
+		"text": [{"cells": ["R1C1"], "value": "Hi", "fontC": "#000000", "size": 0.5}],
+		"line": [{"lines": [["R1C1", "R2C2"]], "outlineC": "#CFCFCF", "width": 0.5}],
+		"rectangle": [{"cells": ["R3C3"], "baseC": "#FFFFFF", "outlineC": "#000000"}],
+		"circle": [{"cells": ["R4C4"], "baseC": "#FFFFFF", "outlineC": "#000000"}],
+		"cage": [{"cells": ["R1C1", "R1C2"], "outlineC": "#000000"}]}`)
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	for _, g := range p.Groups {
+		if !strings.HasPrefix(g.Label(), "region") && len(g.Cells()) != 4 {
+			t.Errorf("Unexpected group %s", g.Label())
+		}
+	}
+
+	// A cage with a value is a rule.
+	_, err = FPuzzlesToPuzzle(`{"size": 4, ` + grid + `,
+		"cage": [{"cells": ["R1C1", "R1C2"], "value": "3"}]}`)
+	unsupported, ok := err.(UnsupportedConstraints)
+	if !ok || strings.Join(unsupported, ",") != "cage" {
+		t.Errorf("Expected an unsupported cage, got %v", err)
+	}
+}
+
+func TestFPuzzlesErrors(t *testing.T) {
+	for _, bad := range []string{
+		`{"size": 16, "grid": []}`,
+		`{"size": 4, "grid": [[{}, {}, {}, {}]]}`,
+		`{"size": 4, "grid": [[{},{},{},{}],[{},{},{},{}],[{},{},{},{}],[{},{},{},{}]],
+		  "killercage": [{"cells": ["R5C1"], "value": "3"}]}`,
+		`{"size": 4, "grid": [[{},{},{},{}],[{},{},{},{}],[{},{},{},{}],[{},{},{},{}]],
+		  "arrow": [{"lines": [["R1C1"]], "cells": ["R1C1"]}]}`,
+		"not f-puzzles data",
+	} {
+		if _, err := FPuzzlesToPuzzle(bad); err == nil {
+			t.Errorf("Expected an error for %s", bad)
+		}
+	}
+}
@@ -0,0 +1,457 @@
+package text
+
+import "fmt"
+import "regexp"
+import "strconv"
+import "strings"
+import "sudoku/base"
+
+// KenKenError describes a single problem found in the textual
+// specification of a KenKen.
+type KenKenError struct {
+	// Line is the line number of the text where the problem was found.
+	Line int
+	// Cage is the identifier of the cage that the problem concerns, or
+	// the empty string if the problem doesn't concern any one cage.
+	Cage string
+	// Issue describes the problem.
+	Issue string
+}
+
+// Error implements the error interface.
+func (e *KenKenError) Error() string {
+	if e.Cage == "" {
+		return fmt.Sprintf("line %d: %s", e.Line, e.Issue)
+	}
+	return fmt.Sprintf("line %d, cage %s: %s", e.Line, e.Cage, e.Issue)
+}
+
+// KenKenErrors is the error that's returned when a KenKen
+// specification has problems.  It lists all of the problems that were
+// found rather than just the first.
+type KenKenErrors []*KenKenError
+
+// Error implements the error interface.
+func (errs KenKenErrors) Error() string {
+	lines := []string{}
+	for _, e := range errs {
+		lines = append(lines, e.Error())
+	}
+	return strings.Join(lines, "\n")
+}
+
+// kenkenCage accumulates what's been read about a single cage.
+type kenkenCage struct {
+	id string
+	// line is the line number on which the cage first appears in the grid.
+	line  int
+	cells []*base.Cell
+	rules []*kenkenRule
+}
+
+// kenkenRule is a single cage rule, like "a: 12 *".
+type kenkenRule struct {
+	line  int
+	id    string
+	value int
+	// op is nil if the rule didn't specify an operator.  That is only
+	// allowed for a single cell cage.
+	op *base.KenKenOperator
+}
+
+// kenkenBuilder collects the grid and cage rules of a KenKen as they
+// are read by one of the KenKen text parsers, then validates them and
+// makes the Puzzle.
+type kenkenBuilder struct {
+	puzzle *base.Puzzle
+	size   int
+	cages  map[string]*kenkenCage
+	// cageOrder has the cages in the order that they first appear.
+	cageOrder []*kenkenCage
+	givens    map[*base.Cell]int
+	// rowLines maps each row of the grid to the line it was read from.
+	rowLines map[int]int
+	rules    []*kenkenRule
+	errors   KenKenErrors
+}
+
+func newKenKenBuilder() *kenkenBuilder {
+	return &kenkenBuilder{
+		puzzle: &base.Puzzle{
+			Grid: make(map[base.GridKey]*base.Cell),
+		},
+		cages:    make(map[string]*kenkenCage),
+		givens:   make(map[*base.Cell]int),
+		rowLines: make(map[int]int),
+	}
+}
+
+func (b *kenkenBuilder) report(line int, cage string, format string, args ...interface{}) {
+	b.errors = append(b.errors, &KenKenError{
+		Line:  line,
+		Cage:  cage,
+		Issue: fmt.Sprintf(format, args...),
+	})
+}
+
+// cell returns the Cell at x, y, creating it if necessary.  line is
+// the line of text that the cell was read from.
+func (b *kenkenBuilder) cell(x, y, line int) *base.Cell {
+	b.rowLines[y] = line
+	key := base.MakeGridKey(x, y)
+	c := b.puzzle.Grid[key]
+	if c == nil {
+		c = &base.Cell{
+			X:      x,
+			Y:      y,
+			Puzzle: b.puzzle,
+		}
+		b.puzzle.Grid[key] = c
+	}
+	b.size = max(b.size, x, y)
+	return c
+}
+
+// addToCage adds the Cell at x, y to the cage identified by id.
+func (b *kenkenBuilder) addToCage(id string, x, y, line int) {
+	cage := b.cages[id]
+	if cage == nil {
+		cage = &kenkenCage{id: id, line: line}
+		b.cages[id] = cage
+		b.cageOrder = append(b.cageOrder, cage)
+	}
+	cage.cells = append(cage.cells, b.cell(x, y, line))
+}
+
+// addGiven notes that the Cell at x, y has the fixed value.
+func (b *kenkenBuilder) addGiven(value, x, y, line int) {
+	b.givens[b.cell(x, y, line)] = value
+	b.size = max(b.size, value)
+}
+
+// addEmpty notes that the Cell at x, y is in no cage.
+func (b *kenkenBuilder) addEmpty(x, y, line int) {
+	b.cell(x, y, line)
+}
+
+// addRule records a cage rule.  op can be nil if no operator was given.
+func (b *kenkenBuilder) addRule(line int, id string, value int, op *base.KenKenOperator) {
+	b.rules = append(b.rules, &kenkenRule{
+		line:  line,
+		id:    id,
+		value: value,
+		op:    op,
+	})
+}
+
+// build validates what's been read and returns the Puzzle.  The error
+// is a KenKenErrors if any problems were found.
+func (b *kenkenBuilder) build() (*base.Puzzle, error) {
+	p := b.puzzle
+	if b.size == 0 {
+		b.report(1, "", "there's no grid")
+		return p, b.errors
+	}
+	complete := true
+	for y := 1; y <= b.size; y++ {
+		for x := 1; x <= b.size; x++ {
+			if p.Grid[base.MakeGridKey(x, y)] == nil {
+				b.report(b.rowLines[y], "", "there's no cell at row %d, column %d", y, x)
+				complete = false
+			}
+		}
+	}
+	if !complete {
+		return p, b.errors
+	}
+
+	for _, cage := range b.cageOrder {
+		g := base.NewGroup(p).SetLabel(cage.id)
+		for _, c := range cage.cells {
+			g.AddCell(c)
+		}
+		p.Groups = append(p.Groups, g)
+	}
+
+	p.Size = b.size
+	p.Universe = base.Universe(b.size)
+	for _, c := range p.Grid {
+		c.Possibilities = p.Universe
+	}
+
+	p.AddLineGroups()
+
+	for y := 1; y <= p.Size; y++ {
+		for x := 1; x <= p.Size; x++ {
+			c := p.Cell(x, y)
+			if v, ok := b.givens[c]; ok {
+				c.MustBe(v, base.Given, nil)
+			}
+		}
+	}
+
+	for _, rule := range b.rules {
+		cage := b.cages[rule.id]
+		if cage == nil {
+			b.report(rule.line, rule.id, "there's no cage for this rule")
+			continue
+		}
+		cage.rules = append(cage.rules, rule)
+	}
+
+	for i, cage := range b.cageOrder {
+		g := p.Groups[i]
+		b.validateCage(cage)
+		for _, rule := range cage.rules {
+			op := rule.op
+			if op == nil {
+				op = base.KenKenOperatorSymbols["+"]
+			}
+			g.AddConstraint(base.MakeKenKenConstraint([]*base.KenKenOperator{op}, rule.value))
+		}
+	}
+
+	if len(b.errors) > 0 {
+		return p, b.errors
+	}
+	return p, nil
+}
+
+// validateCage checks a cage for problems.
+func (b *kenkenBuilder) validateCage(cage *kenkenCage) {
+	switch len(cage.rules) {
+	case 0:
+		b.report(cage.line, cage.id, "the cage has no rule")
+		return
+	case 1:
+	default:
+		b.report(cage.rules[1].line, cage.id, "the cage has more than one rule")
+	}
+	if !contiguous(cage.cells) {
+		b.report(cage.line, cage.id, "the cells of the cage are not contiguous")
+	}
+	for _, rule := range cage.rules {
+		if len(cage.cells) == 1 {
+			if rule.value < 1 || rule.value > b.size {
+				b.report(rule.line, cage.id,
+					"the target %d of a single cell cage is not between 1 and %d",
+					rule.value, b.size)
+				continue
+			}
+		} else if rule.op == nil {
+			b.report(rule.line, cage.id, "a cage of %d cells needs an operator",
+				len(cage.cells))
+			continue
+		}
+		op := rule.op
+		if op == nil {
+			op = base.KenKenOperatorSymbols["+"]
+		}
+		if !reachable(cage.cells, b.size, op, rule.value) {
+			b.report(rule.line, cage.id, "no combination of values can reach %s %d",
+				op.Symbol, rule.value)
+		}
+	}
+}
+
+// contiguous returns true if each of the cells can be reached from
+// each of the others by moving horizontally or vertically between the
+// cells.
+func contiguous(cells []*base.Cell) bool {
+	if len(cells) == 0 {
+		return true
+	}
+	in := make(map[base.GridKey]bool)
+	for _, c := range cells {
+		in[base.MakeGridKey(c.X, c.Y)] = true
+	}
+	seen := make(map[base.GridKey]bool)
+	pending := []base.GridKey{base.MakeGridKey(cells[0].X, cells[0].Y)}
+	seen[pending[0]] = true
+	for len(pending) > 0 {
+		k := pending[len(pending)-1]
+		pending = pending[:len(pending)-1]
+		for _, n := range []base.GridKey{
+			base.MakeGridKey(k.X-1, k.Y),
+			base.MakeGridKey(k.X+1, k.Y),
+			base.MakeGridKey(k.X, k.Y-1),
+			base.MakeGridKey(k.X, k.Y+1),
+		} {
+			if in[n] && !seen[n] {
+				seen[n] = true
+				pending = append(pending, n)
+			}
+		}
+	}
+	return len(seen) == len(in)
+}
+
+// maxReachableWork is about the most steps that reachable takes before
+// giving up on telling whether a cage's rule can be met.
+const maxReachableWork = 1 << 20
+
+// reachable returns true if there's some way to fill cells with values
+// from 1 through size such that op applied to those values gives
+// target.  Cells that share a row or column must have different
+// values.  The values of the first cells are only counted through
+// while the rest of the cells could still reach target, and if that
+// takes too long the rule is assumed to be reachable, so that reading a
+// puzzle can't take exponential time.
+func reachable(cells []*base.Cell, size int, op *base.KenKenOperator, target int) bool {
+	values := make([]int, len(cells))
+	work := 0
+	var try func(index int) bool
+	try = func(index int) bool {
+		work += 1
+		if index == len(cells) && (op.Symbol == "Subtraction" || op.Symbol == "Division") {
+			// These try adding or multiplying by each value or
+			// taking it away or dividing by it.
+			work += len(cells) << uint(len(cells))
+		}
+		if work > maxReachableWork {
+			return true
+		}
+		if index == len(cells) {
+			return op.Test(values, target)
+		}
+		if !canReach(values[:index], len(cells)-index, size, op, target) {
+			return false
+		}
+		for v := 1; v <= size; v++ {
+			ok := true
+			for i := 0; i < index; i++ {
+				if values[i] == v &&
+					(cells[i].X == cells[index].X || cells[i].Y == cells[index].Y) {
+					ok = false
+					break
+				}
+			}
+			if !ok {
+				continue
+			}
+			values[index] = v
+			if try(index + 1) {
+				return true
+			}
+		}
+		return false
+	}
+	return try(0)
+}
+
+// canReach returns false if op can't give target whatever values from
+// 1 through size the remaining cells have after those of values.
+func canReach(values []int, remaining int, size int, op *base.KenKenOperator, target int) bool {
+	sum, product := 0, 1
+	for _, v := range values {
+		sum += v
+		product *= v
+	}
+	switch op.Symbol {
+	case "Addition":
+		return sum+remaining <= target && target <= sum+remaining*size
+	case "Multiplication":
+		return target%product == 0 && atMost(target/product, size, remaining)
+	case "Subtraction":
+		// The difference is no more than the sum of the values.
+		return target <= sum+remaining*size
+	case "Division":
+		// The quotient is no more than the product of the values.
+		return atMost((target+product-1)/product, size, remaining)
+	}
+	return true
+}
+
+// atMost returns true if n is no more than size to the power remaining.
+func atMost(n, size, remaining int) bool {
+	limit := 1
+	for i := 0; i < remaining; i++ {
+		if limit >= n {
+			return true
+		}
+		limit *= size
+	}
+	return n <= limit
+}
+
+// LongCageConstraintRegexp matches a cage rule of the KenKen format
+// read by TextToLongKenKen.
+var LongCageConstraintRegexp = regexp.MustCompile(
+	"^[ \t]*(?P<group>[a-zA-Z_][a-zA-Z0-9_]*)[ \t]*:[ \t]*(?P<value>[0-9]+)[ \t]*(?P<op>[-+*/])?[ \t\r]*$")
+
+var cageIdentifierRegexp = regexp.MustCompile("^[a-zA-Z_][a-zA-Z0-9_]*$")
+
+// TextToLongKenKen makes a KenKen from a text specification like that
+// read by TextToKenKen except that the cells of each row of the grid
+// are separated by whitespace and cage identifiers can be more than one
+// character long.  A cage identifier starts with a letter or underscore
+// which can be followed by letters, digits and underscores.  A cell
+// containing a number has that fixed value and a hyphen is a cell that
+// isn't in any cage.  The grid ends with the first empty line.
+// Comments, which start with # and continue to the end of the line, are
+// allowed in the grid and in the cage rules.
+func TextToLongKenKen(text string) (*base.Puzzle, error) {
+	solution, text, err := ReadSolution(text)
+	if err != nil {
+		return nil, err
+	}
+	metadata, text := ReadMetadata(text)
+	b := newKenKenBuilder()
+	b.puzzle.Metadata = metadata
+	row := 0
+	in_grid := true
+	for index, line := range strings.Split(text, "\n") {
+		linenumber := index + 1
+		if strings.TrimSpace(line) == "" {
+			// An empty line after the grid rows ends the grid.
+			if row > 0 {
+				in_grid = false
+			}
+			continue
+		}
+		if i := strings.IndexRune(line, '#'); i >= 0 {
+			line = line[:i]
+		}
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		if !in_grid {
+			m := LongCageConstraintRegexp.FindStringSubmatch(line)
+			if m == nil {
+				b.report(linenumber, "", "can't read cage rule %q", strings.TrimSpace(line))
+			} else if value, err := strconv.Atoi(m[2]); err != nil {
+				b.report(linenumber, m[1], "bad cage value %s: %s", m[2], err)
+			} else {
+				b.addRule(linenumber, m[1], value, base.KenKenOperatorSymbols[m[3]])
+			}
+			continue
+		}
+		row += 1
+		for column, word := range strings.Fields(line) {
+			x := column + 1
+			if word == "-" {
+				b.addEmpty(x, row, linenumber)
+			} else if value, err := strconv.Atoi(word); err == nil {
+				if value < 1 || value > base.MaxValue {
+					return b.puzzle, KenKenErrors{&KenKenError{
+						Line:  linenumber,
+						Issue: fmt.Sprintf("value %d in column %d is out of range", value, x),
+					}}
+				}
+				b.addGiven(value, x, row, linenumber)
+			} else if cageIdentifierRegexp.MatchString(word) {
+				b.addToCage(word, x, row, linenumber)
+			} else {
+				return b.puzzle, KenKenErrors{&KenKenError{
+					Line:  linenumber,
+					Issue: fmt.Sprintf("invalid cell %q in column %d", word, x),
+				}}
+			}
+		}
+	}
+	p, err := b.build()
+	if err == nil {
+		err = setSolution(p, solution)
+	}
+	return p, err
+}
@@ -0,0 +1,80 @@
+package text
+
+import "fmt"
+import "strings"
+import "sudoku/base"
+
+// LineToSudoku returns an unsolved puzzle for a sudoku written as a
+// single line of 81 characters, the rows of the puzzle one after
+// another.  Each character is either a digit giving the value of that
+// cell or a '.' or '0' for an empty cell.  Anything following the 81
+// characters after whitespace is ignored.
+func LineToSudoku(line string) (*base.Puzzle, error) {
+	fields := strings.Fields(line)
+	if len(fields) == 0 {
+		return nil, fmt.Errorf("there's no puzzle")
+	}
+	cells := fields[0]
+	if len(cells) != 81 {
+		return nil, fmt.Errorf("a sudoku line should have 81 characters, not %d", len(cells))
+	}
+	p := base.NewEmptySudoku()
+	for i, c := range cells {
+		switch c {
+		case '.', '0':
+			break
+		case '1', '2', '3', '4', '5', '6', '7', '8', '9':
+			if _, err := p.Cell(i%9+1, i/9+1).MustBe(int(c-'0'), base.Given, nil); err != nil {
+				return p, fmt.Errorf("character %d: %s", i+1, err)
+			}
+			break
+		default:
+			return p, fmt.Errorf("invalid input character at character %d: 0x%02x",
+				i+1, int(c))
+		}
+	}
+	return p, nil
+}
+
+// LinesToSudokus reads any number of sudokus in the format read by
+// LineToSudoku, one per line.  Empty lines and lines starting with #
+// are ignored.  The Metadata of a header block at the start, as read
+// by ReadMetadata, is given to every puzzle.
+func LinesToSudokus(text string) ([]*base.Puzzle, error) {
+	metadata, text := ReadMetadata(text)
+	puzzles := []*base.Puzzle{}
+	for index, line := range strings.Split(text, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		p, err := LineToSudoku(line)
+		if err != nil {
+			return puzzles, fmt.Errorf("line %d: %s", index+1, err)
+		}
+		for key, value := range metadata {
+			p.SetMetadata(key, value)
+		}
+		puzzles = append(puzzles, p)
+	}
+	return puzzles, nil
+}
+
+// SudokuToLine writes the givens of a sudoku in the format read by
+// LineToSudoku, with a '.' for each empty cell.
+func SudokuToLine(p *base.Puzzle) (string, error) {
+	if p.Size != 9 {
+		return "", fmt.Errorf("a sudoku must have 9 rows and columns, not %d", p.Size)
+	}
+	var b strings.Builder
+	for y := 1; y <= p.Size; y++ {
+		for x := 1; x <= p.Size; x++ {
+			if given, v := p.Cell(x, y).IsGiven(); given {
+				fmt.Fprintf(&b, "%d", v)
+			} else {
+				b.WriteString(".")
+			}
+		}
+	}
+	return b.String(), nil
+}
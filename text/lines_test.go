@@ -0,0 +1,59 @@
+package text
+
+import "testing"
+
+func TestLineToSudoku(t *testing.T) {
+	line := "...7....." + "1........" + "...43.2.." + "........6" + "...5.9..." +
+		"......418" + "....81..." + "..2....5." + ".4....3.."
+	p1, err := LineToSudoku(line)
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	p2, err := TextToSudoku(`
+		---7-----
+		1--------
+		---43-2--
+		--------6
+		---5-9---
+		------418
+		----81---
+		--2----5-
+		-4----3--
+	`)
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	checkSamePuzzle(t, "line", p1, p2)
+	written, err := SudokuToLine(p1)
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	if written != line {
+		t.Errorf("SudokuToLine: want %s, got %s", line, written)
+	}
+}
+
+func TestLinesToSudokus(t *testing.T) {
+	puzzles, err := LinesToSudokus(`
+# Two puzzles, the second with zeros for empty cells.
+2.....459....7..3.6.59...1.3..89...1..2...9..1...27..5.1...93.4.2..3....583.....2
+150009030370060024000503700945080100000012400080400097800200670520601900006800003   rated easy
+`)
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	if len(puzzles) != 2 {
+		t.Fatalf("Expected 2 puzzles, got %d", len(puzzles))
+	}
+	for i, p := range puzzles {
+		if err := p.DoConstraints(); err != nil {
+			t.Errorf("Puzzle %d: error during DoConstraints: %s", i, err)
+		}
+		if !p.IsSolved() {
+			t.Errorf("Puzzle %d not solved", i)
+		}
+	}
+	if _, err := LinesToSudokus("\n123\n"); err == nil || err.Error() != "line 2: a sudoku line should have 81 characters, not 3" {
+		t.Errorf("Wrong error for a short line: %v", err)
+	}
+}
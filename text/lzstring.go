@@ -0,0 +1,134 @@
+package text
+
+// A decoder for the base64 encoding of the lz-string compression
+// library, which f-puzzles uses to put puzzles in URLs.
+
+import "fmt"
+import "strings"
+import "unicode/utf16"
+
+const lzBase64Alphabet = "ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789+/="
+
+// lzBitReader reads the bits of lz-string's base64 encoding.  Each
+// character carries six bits, most significant bit first.
+type lzBitReader struct {
+	input    string
+	index    int
+	value    int
+	position int
+}
+
+func (r *lzBitReader) nextCharacter() error {
+	if r.index >= len(r.input) {
+		return fmt.Errorf("lz-string data ended unexpectedly")
+	}
+	c := r.input[r.index]
+	v := strings.IndexByte(lzBase64Alphabet, c)
+	if v < 0 {
+		return fmt.Errorf("invalid character %q in lz-string data", c)
+	}
+	r.index += 1
+	r.value = v
+	r.position = 32
+	return nil
+}
+
+// bits reads an n bit number, which lz-string writes least significant
+// bit first.
+func (r *lzBitReader) bits(n int) (int, error) {
+	result := 0
+	for power := 1; power < 1<<uint(n); power <<= 1 {
+		if r.position == 0 {
+			if err := r.nextCharacter(); err != nil {
+				return 0, err
+			}
+		}
+		if r.value&r.position != 0 {
+			result |= power
+		}
+		r.position >>= 1
+	}
+	return result, nil
+}
+
+// lzDecompressFromBase64 reverses lz-string's compressToBase64.
+func lzDecompressFromBase64(input string) (string, error) {
+	r := &lzBitReader{input: strings.TrimSpace(input)}
+	// The first three dictionary entries are the codes for an 8 bit
+	// character, a 16 bit character and the end of the data.
+	dictionary := [][]uint16{nil, nil, nil}
+	enlargeIn := 4
+	numBits := 3
+	var w []uint16
+	result := []uint16{}
+
+	readCharacter := func(code int) ([]uint16, error) {
+		size := 8
+		if code == 1 {
+			size = 16
+		}
+		c, err := r.bits(size)
+		if err != nil {
+			return nil, err
+		}
+		return []uint16{uint16(c)}, nil
+	}
+
+	code, err := r.bits(2)
+	if err != nil {
+		return "", err
+	}
+	switch code {
+	case 0, 1:
+		w, err = readCharacter(code)
+		if err != nil {
+			return "", err
+		}
+	case 2:
+		return "", nil
+	default:
+		return "", fmt.Errorf("invalid lz-string data")
+	}
+	dictionary = append(dictionary, w)
+	result = append(result, w...)
+
+	for {
+		code, err := r.bits(numBits)
+		if err != nil {
+			return "", err
+		}
+		switch code {
+		case 0, 1:
+			c, err := readCharacter(code)
+			if err != nil {
+				return "", err
+			}
+			dictionary = append(dictionary, c)
+			code = len(dictionary) - 1
+			enlargeIn -= 1
+		case 2:
+			return string(utf16.Decode(result)), nil
+		}
+		if enlargeIn == 0 {
+			enlargeIn = 1 << uint(numBits)
+			numBits += 1
+		}
+		var entry []uint16
+		switch {
+		case code < len(dictionary):
+			entry = dictionary[code]
+		case code == len(dictionary):
+			entry = append(append([]uint16{}, w...), w[0])
+		default:
+			return "", fmt.Errorf("invalid lz-string data")
+		}
+		result = append(result, entry...)
+		dictionary = append(dictionary, append(append([]uint16{}, w...), entry[0]))
+		enlargeIn -= 1
+		w = entry
+		if enlargeIn == 0 {
+			enlargeIn = 1 << uint(numBits)
+			numBits += 1
+		}
+	}
+}
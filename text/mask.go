@@ -0,0 +1,72 @@
+package text
+
+import "fmt"
+import "sudoku/base"
+
+// TextToMask reads a grid that marks cells of a sudoku, for example
+// where the givens of a generated puzzle must be.  It's written like
+// the grid of TextToSudoku but with an 'x' for a marked cell and a '-'
+// for an unmarked one.  Spaces and tabs are ignored and a '#' starts a
+// comment.  The grid must have nine rows of nine cells.
+func TextToMask(text string) (map[base.GridKey]bool, error) {
+	mask := make(map[base.GridKey]bool)
+	linenumber := 1
+	linecharnumber := 0
+	row := 1
+	column := 1
+	comment := false
+
+	new_line := func() error {
+		if column > 1 {
+			if column != 10 {
+				return fmt.Errorf("line %d has %d cells rather than 9", linenumber, column-1)
+			}
+			row += 1
+			column = 1
+		}
+		linenumber += 1
+		linecharnumber = 0
+		return nil
+	}
+
+	for _, c := range text {
+		linecharnumber += 1
+		if comment {
+			if c == '\n' {
+				comment = false
+				if err := new_line(); err != nil {
+					return nil, err
+				}
+			}
+			continue
+		}
+		switch c {
+		case '#':
+			comment = true
+		case ' ', '\t', '\r':
+			// Ignore
+		case '\n':
+			if err := new_line(); err != nil {
+				return nil, err
+			}
+		case 'x', 'X', '-':
+			if row > 9 || column > 9 {
+				return nil, fmt.Errorf("too many cells at line %d, character %d", linenumber, linecharnumber)
+			}
+			if c != '-' {
+				mask[base.MakeGridKey(column, row)] = true
+			}
+			column += 1
+		default:
+			return nil, fmt.Errorf("invalid mask character at line %d, character %d: 0x%02x",
+				linenumber, linecharnumber, int(c))
+		}
+	}
+	if err := new_line(); err != nil {
+		return nil, err
+	}
+	if row != 10 {
+		return nil, fmt.Errorf("the mask has %d rows rather than 9", row-1)
+	}
+	return mask, nil
+}
@@ -0,0 +1,38 @@
+package text
+
+import "sudoku/base"
+import "testing"
+
+func TestTextToMask(t *testing.T) {
+	mask, err := TextToMask(`
+		# A heart
+		-xxx-xxx-
+		xx-xxx-xx
+		x---x---x
+		x-------x
+		-x-----x-
+		-xx---xx-
+		--xx-xx--
+		---xxx---   # the point
+		----x----
+	`)
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	if len(mask) != 32 {
+		t.Errorf("Expected 32 marked cells, got %d", len(mask))
+	}
+	if !mask[base.MakeGridKey(5, 9)] || mask[base.MakeGridKey(1, 1)] {
+		t.Errorf("The wrong cells are marked")
+	}
+	for _, bad := range []string{
+		"xxxxxxxxx\n",
+		"xxxxxxxxx\nxxxx\n",
+		"xxxxxxxxxx\n",
+		"---------\n---------\n---------\n---------\n---------\n---------\n---------\n---------\n----5----\n",
+	} {
+		if _, err := TextToMask(bad); err == nil {
+			t.Errorf("Expected an error for %q", bad)
+		}
+	}
+}
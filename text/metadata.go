@@ -0,0 +1,55 @@
+package text
+
+import "fmt"
+import "regexp"
+import "strings"
+import "sudoku/base"
+
+// MetadataRegexp matches a "key: value" line of a metadata header.
+var MetadataRegexp = regexp.MustCompile(
+	"^[ \t]*(?P<key>[a-zA-Z][a-zA-Z0-9_-]*)[ \t]*:[ \t]*(?P<value>.*?)[ \t\r]*$")
+
+// ReadMetadata reads the header block at the start of text.  The header
+// is made up of "key: value" lines, for example
+//
+//	title: Binary Fusion
+//	author: Shye
+//
+// which can be mixed with empty lines and # comments.  It ends at the
+// first other line.  Keys are converted to lower case.  The metadata
+// is returned, or nil if there was none, along with text with each
+// header line made empty so that the line numbers of the rest of the
+// text don't change.
+func ReadMetadata(text string) (map[string]string, string) {
+	var metadata map[string]string
+	lines := strings.Split(text, "\n")
+	for i, line := range lines {
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" || strings.HasPrefix(trimmed, "#") {
+			continue
+		}
+		m := MetadataRegexp.FindStringSubmatch(line)
+		if m == nil {
+			break
+		}
+		if metadata == nil {
+			metadata = make(map[string]string)
+		}
+		metadata[strings.ToLower(m[1])] = m[2]
+		lines[i] = ""
+	}
+	return metadata, strings.Join(lines, "\n")
+}
+
+// writeMetadata writes the Puzzle's Metadata as a header block.
+func writeMetadata(b *strings.Builder, p *base.Puzzle) {
+	keys := p.MetadataKeys()
+	for _, key := range keys {
+		// A value has to fit on one line.
+		value := strings.Join(strings.Fields(p.Metadata[key]), " ")
+		fmt.Fprintf(b, "%s: %s\n", key, value)
+	}
+	if len(keys) > 0 {
+		b.WriteString("\n")
+	}
+}
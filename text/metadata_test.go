@@ -0,0 +1,76 @@
+package text
+
+import "testing"
+
+func TestReadMetadata(t *testing.T) {
+	metadata, rest := ReadMetadata(`
+		# A comment
+		Title: Binary Fusion
+		source:  https://app.crackingthecryptic.com/rJDLMnH7LB
+
+		--5-2-6--
+		a: 1`)
+	want := map[string]string{
+		"title":  "Binary Fusion",
+		"source": "https://app.crackingthecryptic.com/rJDLMnH7LB",
+	}
+	if len(metadata) != len(want) {
+		t.Errorf("want %v, got %v", want, metadata)
+	}
+	for key, value := range want {
+		if metadata[key] != value {
+			t.Errorf("%s: want %q, got %q", key, value, metadata[key])
+		}
+	}
+	expect := "\n\t\t# A comment\n\n\n\n\t\t--5-2-6--\n\t\ta: 1"
+	if rest != expect {
+		t.Errorf("want %q, got %q", expect, rest)
+	}
+
+	metadata, _ = ReadMetadata("--5-2-6--\ntitle: not a header")
+	if metadata != nil {
+		t.Errorf("Expected no metadata, got %v", metadata)
+	}
+}
+
+func TestMetadataRoundTrip(t *testing.T) {
+	p, err := TextToKenKen(`
+		title: Single cells
+		difficulty: easy
+
+		aab
+		cdb
+		edd
+
+		a: 3 +
+		b: 3 /
+		c: 3
+		d: 7 +
+		e: 1
+	`)
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	if p.Metadata["title"] != "Single cells" || p.Metadata["difficulty"] != "easy" {
+		t.Errorf("Wrong metadata %v", p.Metadata)
+	}
+	written, err := KenKenToText(p)
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	p2, err := TextToKenKen(written)
+	if err != nil {
+		t.Fatalf("%s:\n%s", err, written)
+	}
+	checkSamePuzzle(t, "metadata", p, p2)
+	if len(p2.Metadata) != 2 || p2.Metadata["title"] != "Single cells" {
+		t.Errorf("Metadata wasn't written:\n%s", written)
+	}
+
+	// Line numbers in errors still count the header lines.
+	_, err = TextToKenKen("title: Bad\n\naab\ncdb\nedd\n\na: 3 +\nb: 3 /\nc: 3\nd: 7 +\n")
+	errs, ok := err.(KenKenErrors)
+	if !ok || len(errs) != 1 || errs[0].Line != 5 || errs[0].Cage != "e" {
+		t.Errorf("Expected a missing rule for cage e on line 5, got %v", err)
+	}
+}
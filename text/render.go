@@ -0,0 +1,271 @@
+package text
+
+import "fmt"
+import "math"
+import "strings"
+import "sudoku/base"
+
+// RenderOption selects optional features of Render.
+type RenderOption int
+
+const (
+	// RenderColor uses ANSI escape sequences to show the values of
+	// givens in bold blue and the values of deduced cells in green.
+	RenderColor RenderOption = iota
+)
+
+const (
+	ansiGiven   = "\x1b[1;34m"
+	ansiDeduced = "\x1b[32m"
+	ansiCursor  = "\x1b[7m"
+	ansiReset   = "\x1b[0m"
+)
+
+// The weights of the lines that Render draws.
+const (
+	noLine = iota
+	lightLine
+	heavyLine
+)
+
+// boxJunctions are the box drawing characters whose up, right, down and
+// left arms have the weights of the base 3 digits of their index.
+var boxJunctions = []rune(" ╴╸╷┐┑╻┒┓╶─╾┌┬┭┎┰┱╺╼━┍┮┯┏┲┳╵┘┙│┤┥╽┧┪└┴┵├┼┽┟╁╅┕┶┷┝┾┿┢╆╈╹┚┛╿┦┩┃┨┫┖┸┹┞╀╃┠╂╉┗┺┻┡╄╇┣╊╋")
+
+func boxJunction(up, right, down, left int) rune {
+	return boxJunctions[up*27+right*9+down*3+left]
+}
+
+// isRegion returns true if the Group is a region whose outline Render
+// draws: a box or jigsaw region, a KenKen cage or a killer cage, as
+// opposed to a row, a column, a diagonal or a line like a thermometer.
+func isRegion(p *base.Puzzle, g *base.Group) bool {
+	if isLine(p, g) || isCage(g) {
+		return !isLine(p, g)
+	}
+	diagonal, anti_diagonal := true, true
+	for _, c := range g.Cells() {
+		diagonal = diagonal && c.X == c.Y
+		anti_diagonal = anti_diagonal && c.X+c.Y == p.Size+1
+	}
+	if diagonal || anti_diagonal {
+		return false
+	}
+	for _, c := range g.Constraints() {
+		if _, ok := c.(*base.CageConstraint); ok {
+			return true
+		}
+		if c.Name() == base.HereThenNotElsewhereConstraint.Name() {
+			return true
+		}
+	}
+	return false
+}
+
+// cageLabel returns the target of a KenKen or killer cage as written in
+// the corner of its first cell, or "" if the Group isn't a cage.
+func cageLabel(g *base.Group) string {
+	for _, c := range g.Constraints() {
+		switch c := c.(type) {
+		case *base.KenKenCageConstraint:
+			if len(g.Cells()) == 1 || len(c.Operators()) != 1 {
+				return fmt.Sprintf("%d", c.Expect())
+			}
+			return fmt.Sprintf("%d%s", c.Expect(), operatorSymbol(c.Operators()[0]))
+		case *base.CageConstraint:
+			if c.Sum() != 0 {
+				return fmt.Sprintf("%d", c.Sum())
+			}
+		}
+	}
+	return ""
+}
+
+// Render draws the Puzzle for a terminal using box drawing characters.
+// The outlines of the boxes of a sudoku and the cages of a KenKen are
+// drawn with heavy lines and a cage's target is written in the top
+// left corner of its first cell.  A solved cell shows its value and an
+// unsolved cell shows its possible values as a small grid, 1 2 3 in
+// the top row, 4 5 6 in the next and so on.
+func Render(p *base.Puzzle, options ...RenderOption) string {
+	return RenderBoard(p, nil, nil, options...)
+}
+
+// RenderBoard is like Render but for a game in progress.  It marks the
+// cursor Cell, in reverse video with RenderColor and otherwise with >
+// and < on either side of its middle line.  The cells in marks are
+// drawn with those pencil marks as a small grid, even if there's only
+// one, rather than with their possible values.  The cursor and marks
+// can be nil.
+func RenderBoard(p *base.Puzzle, cursor *base.Cell, marks map[*base.Cell]base.ValueSet, options ...RenderOption) string {
+	color := false
+	for _, o := range options {
+		color = color || o == RenderColor
+	}
+	// The possible values are drawn in a grid of columns by rows.
+	columns := int(math.Ceil(math.Sqrt(float64(p.Universe.Len()))))
+	if columns < 1 {
+		columns = 1
+	}
+	rows := (p.Universe.Len() + columns - 1) / columns
+	width := 2*columns + 1
+
+	regions := make(map[*base.Cell][]*base.Group)
+	labels := make(map[*base.Cell]string)
+	for _, g := range p.Groups {
+		if !isRegion(p, g) {
+			continue
+		}
+		for _, c := range g.Cells() {
+			regions[c] = append(regions[c], g)
+		}
+		if label := cageLabel(g); label != "" {
+			first := g.Cells()[0]
+			for _, c := range g.Cells() {
+				if c.Y < first.Y || (c.Y == first.Y && c.X < first.X) {
+					first = c
+				}
+			}
+			labels[first] = label
+			if len(label)+1 > width {
+				width = len(label) + 1
+			}
+		}
+	}
+	label_line := 0
+	if len(labels) > 0 {
+		label_line = 1
+	}
+
+	// separated returns true if a region has one of the cells but
+	// not the other.
+	separated := func(c1, c2 *base.Cell) bool {
+		for _, cells := range [][2]*base.Cell{{c1, c2}, {c2, c1}} {
+			for _, g := range regions[cells[0]] {
+				if !g.HasCell(cells[1]) {
+					return true
+				}
+			}
+		}
+		return false
+	}
+	// vertical returns the weight of the line to the left of column x
+	// in row y.
+	vertical := func(x, y int) int {
+		switch {
+		case y < 1 || y > p.Size:
+			return noLine
+		case x == 1 || x == p.Size+1:
+			return heavyLine
+		case separated(p.Cell(x-1, y), p.Cell(x, y)):
+			return heavyLine
+		}
+		return lightLine
+	}
+	// horizontal returns the weight of the line above row y in column
+	// x.
+	horizontal := func(x, y int) int {
+		switch {
+		case x < 1 || x > p.Size:
+			return noLine
+		case y == 1 || y == p.Size+1:
+			return heavyLine
+		case separated(p.Cell(x, y-1), p.Cell(x, y)):
+			return heavyLine
+		}
+		return lightLine
+	}
+
+	var b strings.Builder
+	for y := 1; y <= p.Size+1; y++ {
+		// The line above row y.
+		for x := 1; x <= p.Size+1; x++ {
+			b.WriteRune(boxJunction(vertical(x, y-1), horizontal(x, y),
+				vertical(x, y), horizontal(x-1, y)))
+			if x <= p.Size {
+				line := "─"
+				if horizontal(x, y) == heavyLine {
+					line = "━"
+				}
+				b.WriteString(strings.Repeat(line, width))
+			}
+		}
+		b.WriteString("\n")
+		if y > p.Size {
+			break
+		}
+		for line := 0; line < label_line+rows; line++ {
+			for x := 1; x <= p.Size; x++ {
+				if vertical(x, y) == heavyLine {
+					b.WriteString("┃")
+				} else {
+					b.WriteString("│")
+				}
+				c := p.Cell(x, y)
+				var inside string
+				if vs, found := marks[c]; found && line >= label_line {
+					inside = renderCandidates(vs, line-label_line, columns, width)
+				} else {
+					inside = renderCellLine(c, line-label_line, labels[c], columns, rows, width, color)
+				}
+				if c == cursor {
+					inside = renderCursorLine(inside, line-label_line == (rows-1)/2, color)
+				}
+				b.WriteString(inside)
+			}
+			b.WriteString("┃\n")
+		}
+	}
+	return b.String()
+}
+
+// renderCellLine returns one line of the inside of a cell.  Line -1 is
+// the line for the target of a cage.
+func renderCellLine(c *base.Cell, line int, label string, columns int, rows int, width int, color bool) string {
+	if line < 0 {
+		return fmt.Sprintf("%-*s", width, label)
+	}
+	if solved, v := c.IsSolved(); solved {
+		if line != (rows-1)/2 {
+			return strings.Repeat(" ", width)
+		}
+		value := fmt.Sprintf("%d", v)
+		if color {
+			if given, _ := c.IsGiven(); given {
+				value = ansiGiven + value + ansiReset
+			} else {
+				value = ansiDeduced + value + ansiReset
+			}
+		}
+		return strings.Repeat(" ", (width-1)/2) + value + strings.Repeat(" ", width-1-(width-1)/2)
+	}
+	return renderCandidates(c.Possibilities, line, columns, width)
+}
+
+// renderCandidates returns one line of the small grid of the values in
+// vs.
+func renderCandidates(vs base.ValueSet, line int, columns int, width int) string {
+	pad := (width - 2*columns + 1) / 2
+	values := []string{}
+	for column := 0; column < columns; column++ {
+		v := line*columns + column + 1
+		if vs.HasValue(v) {
+			values = append(values, fmt.Sprintf("%d", v))
+		} else {
+			values = append(values, " ")
+		}
+	}
+	return strings.Repeat(" ", pad) + strings.Join(values, " ") +
+		strings.Repeat(" ", width-pad-2*columns+1)
+}
+
+// renderCursorLine marks a line of the inside of the cursor Cell.
+func renderCursorLine(inside string, middle bool, color bool) string {
+	if color {
+		return ansiCursor + strings.Replace(inside, ansiReset, ansiReset+ansiCursor, -1) + ansiReset
+	}
+	if middle {
+		return ">" + inside[1:len(inside)-1] + "<"
+	}
+	return inside
+}
@@ -0,0 +1,103 @@
+package text
+
+import "strings"
+import "testing"
+import "sudoku/base"
+
+func TestRenderKenKen(t *testing.T) {
+	p, err := TextToKenKen(`
+		aab
+		cdb
+		edd
+
+		a: 3 +
+		b: 3 /
+		c: 3
+		d: 7 +
+		e: 1
+	`)
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	got := Render(p)
+	t.Logf("\n%s", got)
+	want := strings.Join([]string{
+		"┏━━━━━┯━━━━━┳━━━━━┓",
+		"┃3+   │     ┃3/   ┃",
+		"┃ 1 2 │ 1 2 ┃ 1 2 ┃",
+		"┃ 3   │ 3   ┃ 3   ┃",
+		"┣━━━━━╈━━━━━╉─────┨",
+		"┃3    ┃7+   ┃     ┃",
+		"┃ 1 2 ┃ 1 2 ┃ 1 2 ┃",
+		"┃ 3   ┃ 3   ┃ 3   ┃",
+		"┣━━━━━╉─────╄━━━━━┫",
+		"┃1    ┃     │     ┃",
+		"┃ 1 2 ┃ 1 2 │ 1 2 ┃",
+		"┃ 3   ┃ 3   │ 3   ┃",
+		"┗━━━━━┻━━━━━┷━━━━━┛",
+		""}, "\n")
+	if got != want {
+		t.Errorf("Render: want\n%s\ngot\n%s", want, got)
+	}
+}
+
+func TestRenderSudoku(t *testing.T) {
+	p, err := TextToSudoku(`
+		---7-----
+		1--------
+		---43-2--
+		--------6
+		---5-9---
+		------418
+		----81---
+		--2----5-
+		-4----3--
+	`)
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	p.Cell(1, 1).CantBe(1, base.Pick, nil)
+	got := Render(p, RenderColor)
+	lines := strings.Split(got, "\n")
+	if want := 9*4 + 2; len(lines) != want {
+		t.Fatalf("want %d lines, got %d:\n%s", want, len(lines), got)
+	}
+	if want := "┏━━━━━━━┯━━━━━━━┯━━━━━━━┳"; !strings.HasPrefix(lines[0], want) {
+		t.Errorf("The top border should start %q: %q", want, lines[0])
+	}
+	// The given 7 in row 1, column 4 is on the middle line of its cell.
+	if !strings.Contains(lines[2], "┃   "+ansiGiven+"7"+ansiReset+"   │") {
+		t.Errorf("The given 7 wasn't drawn: %q", lines[2])
+	}
+	// Cell(1, 1) can't be 1.
+	if want := "┃   2 3 │"; !strings.HasPrefix(lines[1], want) {
+		t.Errorf("Cell(1, 1)'s candidates: want %q, got %q", want, lines[1])
+	}
+}
+
+func TestRenderBoard(t *testing.T) {
+	p, err := TextToKenKen(`
+		aab
+		cdb
+		edd
+
+		a: 3 +
+		b: 3 /
+		c: 3
+		d: 7 +
+		e: 1
+	`)
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	p.Cell(1, 1).MustBe(2, base.Pick, nil)
+	marks := map[*base.Cell]base.ValueSet{
+		p.Cell(2, 1): base.NewValueSet([]int{1}),
+	}
+	lines := strings.Split(RenderBoard(p, p.Cell(1, 1), marks), "\n")
+	// The cursor's value is on the second line of the cell, after the
+	// cage's target, and a single pencil mark isn't drawn as a value.
+	if want := "┃> 2 <│ 1   ┃"; !strings.HasPrefix(lines[2], want) {
+		t.Errorf("want %q, got %q", want, lines[2])
+	}
+}
@@ -0,0 +1,109 @@
+package text
+
+import "fmt"
+import "regexp"
+import "strings"
+import "sudoku/base"
+
+// SolutionRegexp matches the line that starts the solution section.
+var SolutionRegexp = regexp.MustCompile("^[ \t]*(?i:solution)[ \t]*:[ \t\r]*(#.*)?$")
+
+// ReadSolution reads the optional solution section of text.  The
+// section is a "solution:" line followed by the rows of the solved
+// grid, one digit for each cell:
+//
+//	solution:
+//	271683459
+//	498571236
+//	...
+//
+// As in TolerantTextToSudoku, spaces, # comments and the characters
+// that draw borders are ignored.  The section ends at the first empty
+// line after its rows.  The rows are returned, or nil if there's no
+// solution section, along with text with each line of the section made
+// empty so that the line numbers of the rest of the text don't change.
+func ReadSolution(text string) ([][]int, string, error) {
+	lines := strings.Split(text, "\n")
+	start := -1
+	for i, line := range lines {
+		if SolutionRegexp.MatchString(line) {
+			start = i
+			break
+		}
+	}
+	if start < 0 {
+		return nil, text, nil
+	}
+	lines[start] = ""
+	rows := [][]int{}
+	for i := start + 1; i < len(lines); i++ {
+		line := lines[i]
+		if strings.TrimSpace(line) == "" && len(rows) > 0 {
+			break
+		}
+		lines[i] = ""
+		if j := strings.IndexRune(line, '#'); j >= 0 {
+			line = line[:j]
+		}
+		if isBorderLine(line) {
+			continue
+		}
+		row := []int{}
+		for _, c := range line {
+			switch {
+			case c == ' ', c == '\t', c == '\r', isBoxDrawing(c):
+				continue
+			case c >= '1' && c <= '9':
+				row = append(row, int(c-'0'))
+			default:
+				return nil, text, fmt.Errorf("line %d: can't read the character %q in the solution",
+					i+1, c)
+			}
+		}
+		rows = append(rows, row)
+	}
+	if len(rows) == 0 {
+		return nil, text, fmt.Errorf("line %d: the solution has no rows", start+1)
+	}
+	return rows, strings.Join(lines, "\n"), nil
+}
+
+// setSolution sets the Solution of the Puzzle from the rows read by
+// ReadSolution.
+func setSolution(p *base.Puzzle, rows [][]int) error {
+	if rows == nil {
+		return nil
+	}
+	if len(rows) != p.Size {
+		return fmt.Errorf("the solution has %d rows rather than %d", len(rows), p.Size)
+	}
+	p.Solution = make(map[base.GridKey]int)
+	for y, row := range rows {
+		if len(row) != p.Size {
+			return fmt.Errorf("row %d of the solution has %d cells rather than %d",
+				y+1, len(row), p.Size)
+		}
+		for x, v := range row {
+			if v > p.Size {
+				return fmt.Errorf("the value %d in row %d of the solution is out of range", v, y+1)
+			}
+			p.Solution[base.MakeGridKey(x+1, y+1)] = v
+		}
+	}
+	return nil
+}
+
+// writeSolution writes the Puzzle's Solution, if it has one, as a
+// solution section.
+func writeSolution(b *strings.Builder, p *base.Puzzle) {
+	if p.Solution == nil {
+		return
+	}
+	b.WriteString("\nsolution:\n")
+	for y := 1; y <= p.Size; y++ {
+		for x := 1; x <= p.Size; x++ {
+			fmt.Fprintf(b, "%d", p.Solution[base.MakeGridKey(x, y)])
+		}
+		b.WriteString("\n")
+	}
+}
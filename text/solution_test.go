@@ -0,0 +1,85 @@
+package text
+
+import "testing"
+import "sudoku/base"
+
+func TestSolutionSection(t *testing.T) {
+	p, err := TextToSudoku(`
+		2-----459
+		----7--3-
+		6-59---1-
+		3--89---1
+		--2---9--
+		1---27--5
+		-1---93-4
+		-2--3----
+		583-----2
+
+		solution:   # from sudoku_1.txt
+		271 683 459
+		498 571 236
+		635 942 718
+		------------
+		347 895 621
+		852 416 973
+		169 327 845
+		------------
+		716 259 384
+		924 138 567
+		583 764 192
+	`)
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	if p.Solution == nil {
+		t.Fatalf("No solution was read")
+	}
+	if err := p.DoConstraints(); err != nil {
+		t.Fatalf("Error during DoConstraints: %s", err.Error())
+	}
+	mismatches, err := p.Verify()
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	for _, m := range mismatches {
+		t.Errorf("%s", m)
+	}
+
+	for _, bad := range []string{
+		"123\n231\n312\n\nsolution:\n123\n231\n",
+		"solution:\n123\n2x1\n312\n",
+		"solution:\n\n",
+	} {
+		if _, err := TextToSudoku(bad); err == nil {
+			t.Errorf("Expected an error for %q", bad)
+		}
+	}
+}
+
+func TestKenKenSolutionSection(t *testing.T) {
+	p, err := TextToKenKen(`
+		aab
+		cdb
+		edd
+
+		a: 3 +
+		b: 3 /
+		c: 3
+		d: 7 +
+		e: 1
+
+		solution:
+		213
+		321
+		132
+	`)
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	if v := p.Solution[base.MakeGridKey(3, 2)]; v != 1 {
+		t.Errorf("Cell(3, 2) of the solution should be 1, not %d", v)
+	}
+	if _, err := TextToKenKen("ab\nab\n\na: 3 +\nb: 3 +\n\nsolution:\n12\n"); err == nil {
+		t.Errorf("Expected an error for a solution with too few rows")
+	}
+}
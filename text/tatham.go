@@ -0,0 +1,423 @@
+package text
+
+// Support for the game IDs that Simon Tatham's Portable Puzzle
+// Collection uses to describe its "solo" (sudoku) and "keen" (KenKen)
+// puzzles.  A game ID is made up of the puzzle's parameters, a colon,
+// and a description of the puzzle.
+
+import "fmt"
+import "strconv"
+import "strings"
+import "sudoku/base"
+
+// splitGameID separates a game ID into its parameters and description.
+func splitGameID(id string) (string, string, error) {
+	id = strings.TrimSpace(id)
+	i := strings.IndexRune(id, ':')
+	if i < 0 {
+		if strings.ContainsRune(id, '#') {
+			return "", "", fmt.Errorf("%q is a random seed rather than a game ID", id)
+		}
+		return "", "", fmt.Errorf("%q has no colon so it isn't a game ID", id)
+	}
+	return id[:i], id[i+1:], nil
+}
+
+// leadingInt returns the integer at the start of s and the rest of s.
+func leadingInt(s string) (int, string) {
+	i := 0
+	for i < len(s) && s[i] >= '0' && s[i] <= '9' {
+		i += 1
+	}
+	n, _ := strconv.Atoi(s[:i])
+	return n, s[i:]
+}
+
+// SoloToSudoku returns an unsolved puzzle for a solo game ID like
+// "3x3:a5b2_4c...".  Only 9 by 9 sudokus with 3 by 3 boxes are
+// supported.  The X variant, where each diagonal must also contain
+// every value, is supported but jigsaw and killer sudokus are not.
+func SoloToSudoku(id string) (*base.Puzzle, error) {
+	params, desc, err := splitGameID(id)
+	if err != nil {
+		return nil, err
+	}
+	c, rest := leadingInt(params)
+	r := c
+	if strings.HasPrefix(rest, "x") {
+		r, rest = leadingInt(rest[1:])
+	}
+	if c != 3 || r != 3 {
+		return nil, fmt.Errorf("only 3x3 solo puzzles are supported, not %q", params)
+	}
+	diagonals := false
+	for i := 0; i < len(rest); i++ {
+		switch rest[i] {
+		case 'x':
+			diagonals = true
+		case 'j':
+			return nil, fmt.Errorf("jigsaw solo puzzles aren't supported")
+		case 'k':
+			return nil, fmt.Errorf("killer solo puzzles aren't supported")
+		case 'd':
+			// Skip the difficulty level.
+			i += 1
+		}
+	}
+
+	p := base.NewEmptySudoku()
+	if diagonals {
+		p.AddDiagonalGroups()
+	}
+	// The position in the game ID of the description's first character.
+	offset := len(params) + 2
+	cell := 0
+	for i := 0; i < len(desc); {
+		ch := desc[i]
+		switch {
+		case ch >= 'a' && ch <= 'z':
+			cell += int(ch-'a') + 1
+			i += 1
+		case ch == '_':
+			i += 1
+		case ch >= '0' && ch <= '9':
+			start := i
+			var value int
+			value, _ = leadingInt(desc[i:])
+			for i < len(desc) && desc[i] >= '0' && desc[i] <= '9' {
+				i += 1
+			}
+			if value < 1 || value > p.Size {
+				return p, fmt.Errorf("character %d: value %d out of range", offset+start, value)
+			}
+			if cell >= p.Size*p.Size {
+				return p, fmt.Errorf("character %d: too many cells in game description", offset+start)
+			}
+			if err := setGiven(p.Cell(cell%p.Size+1, cell/p.Size+1), value); err != nil {
+				return p, fmt.Errorf("character %d: %s", offset+start, err)
+			}
+			cell += 1
+		default:
+			return p, fmt.Errorf("character %d: invalid character %q in game description", offset+i, ch)
+		}
+	}
+	if cell != p.Size*p.Size {
+		return p, fmt.Errorf("game description has %d cells rather than %d", cell, p.Size*p.Size)
+	}
+	return p, nil
+}
+
+// SudokuToSolo returns the solo game ID for the givens of a sudoku.
+func SudokuToSolo(p *base.Puzzle) (string, error) {
+	if p.Size != 9 {
+		return "", fmt.Errorf("a sudoku must have 9 rows and columns, not %d", p.Size)
+	}
+	var b strings.Builder
+	b.WriteString("3x3:")
+	run := 0
+	previous := false
+	for y := 1; y <= p.Size; y++ {
+		for x := 1; x <= p.Size; x++ {
+			given, v := p.Cell(x, y).IsGiven()
+			if !given {
+				run += 1
+				continue
+			}
+			if run > 0 {
+				b.WriteString(blankRun(run))
+			} else if previous {
+				b.WriteString("_")
+			}
+			fmt.Fprintf(&b, "%d", v)
+			run = 0
+			previous = true
+		}
+	}
+	if run > 0 {
+		b.WriteString(blankRun(run))
+	}
+	return b.String(), nil
+}
+
+// blankRun encodes a run of empty cells as letters: 'a' is one empty
+// cell through 'z' for 26.
+func blankRun(run int) string {
+	s := ""
+	for run > 0 {
+		n := run
+		if n > 26 {
+			n = 26
+		}
+		s += string(rune('a' - 1 + n))
+		run -= n
+	}
+	return s
+}
+
+// keenOperators maps the letters that keen uses for the operation of a
+// cage to our operator symbols.
+var keenOperators = map[byte]string{
+	'a': "+",
+	'm': "*",
+	's': "-",
+	'd': "/",
+}
+
+// keenEdge returns the indices of the two cells on either side of the
+// pos'th internal grid line of a keen puzzle of width w.  The vertical
+// lines come first, in reading order, then the horizontal ones in
+// transposed reading order.
+func keenEdge(pos, w int) (int, int) {
+	if pos < w*(w-1) {
+		y := pos / (w - 1)
+		x := pos % (w - 1)
+		return y*w + x, y*w + x + 1
+	}
+	x := pos/(w-1) - w
+	y := pos % (w - 1)
+	return y*w + x, (y+1)*w + x
+}
+
+// unionFind finds the cages of a keen puzzle.
+type unionFind []int
+
+func (u unionFind) find(i int) int {
+	for u[i] != i {
+		i = u[i]
+	}
+	return i
+}
+
+// merge joins the classes of i and j, keeping the smaller index as the
+// canonical element, which is the order that keen lists clues in.
+func (u unionFind) merge(i, j int) {
+	i, j = u.find(i), u.find(j)
+	if i > j {
+		i, j = j, i
+	}
+	u[j] = i
+}
+
+// KeenToKenKen returns an unsolved KenKen for a keen game ID like
+// "4:_a3b_a_,a7m12s1d2a3".
+func KeenToKenKen(id string) (*base.Puzzle, error) {
+	params, desc, err := splitGameID(id)
+	if err != nil {
+		return nil, err
+	}
+	w, _ := leadingInt(params)
+	if w < 1 || w > base.MaxValue {
+		return nil, fmt.Errorf("keen puzzles of size %q aren't supported", params)
+	}
+	a := w * w
+	// The position in the game ID of the description's first character.
+	// The whole game ID is line 1 of any KenKenErrors.
+	offset := len(params) + 2
+	cages := make(unionFind, a)
+	for i := range cages {
+		cages[i] = i
+	}
+
+	// The block structure gives the number of internal grid lines
+	// between cage boundaries: '_' for none, 'a' for one through 'y'
+	// for 25.  'z' is 25 with no boundary after them.  A letter can be
+	// followed by a repeat count.
+	pos := 0
+	i := 0
+	for i < len(desc) && desc[i] != ',' {
+		ch := desc[i]
+		start := i
+		if ch != '_' && (ch < 'a' || ch > 'z') {
+			return nil, fmt.Errorf("character %d: invalid character %q in keen block structure", offset+i, ch)
+		}
+		i += 1
+		count := 1
+		if i < len(desc) && desc[i] >= '0' && desc[i] <= '9' {
+			count, _ = leadingInt(desc[i:])
+			for i < len(desc) && desc[i] >= '0' && desc[i] <= '9' {
+				i += 1
+			}
+		}
+		for ; count > 0; count-- {
+			run := 0
+			switch {
+			case ch == 'z':
+				run = 25
+			case ch != '_':
+				run = int(ch-'a') + 1
+			}
+			for ; run > 0; run-- {
+				if pos >= 2*w*(w-1) {
+					return nil, fmt.Errorf("character %d: too much data in keen block structure", offset+start)
+				}
+				cages.merge(keenEdge(pos, w))
+				pos += 1
+			}
+			if ch != 'z' {
+				pos += 1
+			}
+		}
+	}
+	if pos != 2*w*(w-1)+1 {
+		return nil, fmt.Errorf("character %d: not enough data in keen block structure", offset+i)
+	}
+	if i >= len(desc) {
+		return nil, fmt.Errorf("character %d: keen game description has no clues", offset+i)
+	}
+	i += 1
+
+	b := newKenKenBuilder()
+	ids := make(map[int]string)
+	for cell := 0; cell < a; cell++ {
+		root := cages.find(cell)
+		if ids[root] == "" {
+			if len(ids) < len(CageLetters) {
+				ids[root] = CageLetters[len(ids) : len(ids)+1]
+			} else {
+				ids[root] = fmt.Sprintf("c%d", len(ids)+1)
+			}
+		}
+		b.addToCage(ids[root], cell%w+1, cell/w+1, 1)
+	}
+	for cell := 0; cell < a; cell++ {
+		if cages.find(cell) != cell {
+			continue
+		}
+		if i >= len(desc) {
+			return nil, fmt.Errorf("character %d: keen game description has too few clues", offset+i)
+		}
+		op, ok := keenOperators[desc[i]]
+		if !ok {
+			return nil, fmt.Errorf("character %d: invalid operation %q in keen clues", offset+i, desc[i])
+		}
+		i += 1
+		if i >= len(desc) || desc[i] < '0' || desc[i] > '9' {
+			return nil, fmt.Errorf("character %d: expected a number in keen clues", offset+i)
+		}
+		var value int
+		value, _ = leadingInt(desc[i:])
+		for i < len(desc) && desc[i] >= '0' && desc[i] <= '9' {
+			i += 1
+		}
+		b.addRule(1, ids[cell], value, base.KenKenOperatorSymbols[op])
+	}
+	if i != len(desc) {
+		return nil, fmt.Errorf("character %d: keen game description has too many clues", offset+i)
+	}
+	return b.build()
+}
+
+// KenKenToKeen returns the keen game ID for a KenKen.  keen puts every
+// cell in a cage, so each given is written as a cage of one cell.
+func KenKenToKeen(p *base.Puzzle) (string, error) {
+	groups, err := kenkenCages(p)
+	if err != nil {
+		return "", err
+	}
+	w := p.Size
+	cage := make([]int, w*w)
+	rules := make(map[int]string)
+	for i := range cage {
+		cage[i] = -1
+	}
+	for _, g := range groups {
+		rule, err := keenRule(g)
+		if err != nil {
+			return "", err
+		}
+		first := w * w
+		for _, c := range g.Cells() {
+			first = min(first, (c.Y-1)*w+c.X-1)
+		}
+		for _, c := range g.Cells() {
+			cage[(c.Y-1)*w+c.X-1] = first
+		}
+		rules[first] = rule
+	}
+	for i := range cage {
+		if cage[i] >= 0 {
+			continue
+		}
+		given, v := p.Cell(i%w+1, i/w+1).IsGiven()
+		if !given {
+			return "", fmt.Errorf("cell %d, %d is in no cage and has no value", i%w+1, i/w+1)
+		}
+		cage[i] = i
+		rules[i] = fmt.Sprintf("a%d", v)
+	}
+
+	structure := ""
+	run := 0
+	for pos := 0; pos <= 2*w*(w-1); pos++ {
+		edge := true
+		if pos < 2*w*(w-1) {
+			p0, p1 := keenEdge(pos, w)
+			edge = cage[p0] != cage[p1]
+		}
+		if !edge {
+			run += 1
+			continue
+		}
+		for run > 25 {
+			structure += "z"
+			run -= 25
+		}
+		if run > 0 {
+			structure += string(rune('a' - 1 + run))
+		} else {
+			structure += "_"
+		}
+		run = 0
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "%d:", w)
+	b.WriteString(compressRuns(structure))
+	b.WriteString(",")
+	for i := range cage {
+		if cage[i] == i {
+			b.WriteString(rules[i])
+		}
+	}
+	return b.String(), nil
+}
+
+// compressRuns replaces each run of more than two of the same
+// character by that character followed by the length of the run.
+func compressRuns(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); {
+		j := i
+		for j < len(s) && s[j] == s[i] {
+			j += 1
+		}
+		if j-i > 2 {
+			fmt.Fprintf(&b, "%c%d", s[i], j-i)
+		} else {
+			b.WriteString(s[i:j])
+		}
+		i = j
+	}
+	return b.String()
+}
+
+// keenRule returns a cage's rule in keen's notation.
+func keenRule(g *base.Group) (string, error) {
+	rule, err := cageRule(g)
+	if err != nil {
+		return "", err
+	}
+	fields := strings.Fields(rule)
+	if len(fields) == 1 {
+		return "a" + fields[0], nil
+	}
+	for letter, symbol := range keenOperators {
+		if symbol == fields[1] {
+			if (letter == 's' || letter == 'd') && len(g.Cells()) != 2 {
+				return "", fmt.Errorf("keen only allows %s in cages of two cells", symbol)
+			}
+			return string(rune(letter)) + fields[0], nil
+		}
+	}
+	return "", fmt.Errorf("keen has no operation for %s", fields[1])
+}
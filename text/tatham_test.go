@@ -0,0 +1,246 @@
+package text
+
+import "fmt"
+import "sudoku/base"
+import "testing"
+
+func TestSolo(t *testing.T) {
+	expect, err := TextToSudoku(`
+		2-----459
+		----7--3-
+		6-59---1-
+		3--89---1
+		--2---9--
+		1---27--5
+		-1---93-4
+		-2--3----
+		583-----2
+	`)
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	id := "3x3:2e4_5_9d7b3a6a5_9c1a3b8_9c1b2c9b1c2_7b5a1c9_3a4a2b3d5_8_3e2"
+	written, err := SudokuToSolo(expect)
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	if written != id {
+		t.Errorf("SudokuToSolo: want %s, got %s", id, written)
+	}
+	p, err := SoloToSudoku(id)
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	checkSamePuzzle(t, "solo", expect, p)
+
+	for _, bad := range []string{
+		"3x3#123456",
+		"2x2:a1b2",
+		"3x3j:a1",
+		"3x3:a1b2",
+		"3x3:" + "z" + "z" + "z" + "zz",
+	} {
+		if _, err := SoloToSudoku(bad); err == nil {
+			t.Errorf("Expected an error for %q", bad)
+		}
+	}
+}
+
+func TestSoloX(t *testing.T) {
+	p, err := SoloToSudoku("3x3x:" + "zzzc")
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	if want, got := 29, len(p.Groups); got != want {
+		t.Errorf("Expected %d groups, got %d", want, got)
+	}
+}
+
+func TestKeen(t *testing.T) {
+	expect, err := TextToKenKen(`
+		aab
+		cdb
+		edd
+
+		a: 3 +
+		b: 3 /
+		c: 3
+		d: 7 +
+		e: 1
+	`)
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	// The internal vertical lines, in reading order, are
+	//   a|a no, a|b yes, c|d yes, d|b yes, e|d yes, d|d no,
+	// and the horizontal ones, in transposed reading order, are
+	//   a|c yes, c|e yes, a|d yes, d|d no, b|b no, b|d yes.
+	id := "3:a_3a__b_,a3d3a3a7a1"
+	written, err := KenKenToKeen(expect)
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	if written != id {
+		t.Errorf("KenKenToKeen: want %s, got %s", id, written)
+	}
+	p, err := KeenToKenKen(id)
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	checkSamePuzzle(t, "keen", expect, p)
+
+	for _, bad := range []string{
+		"3:a_3a__b_",
+		"3:a_3a__b,a3d3a3a7a1",
+		"3:a_3a__b_,a3d3a3a7",
+		"3:a_3a__b_,a3d3a3a7a1a1",
+		"3:a_3a__b_,a3x3a3a7a1",
+	} {
+		if _, err := KeenToKenKen(bad); err == nil {
+			t.Errorf("Expected an error for %q", bad)
+		}
+	}
+}
+
+func TestKeenDiscoParty(t *testing.T) {
+	p, err := TextToKenKen(`
+		abccdd
+		abccee
+		affcgg
+		5ffhg1
+		iijhkk
+		i1jllk
+
+		a:  12 *
+		b:  20 *
+		c:  23 +
+		d:   5 +
+		e:  12 *
+		f:  72 *
+		g:  12 *
+		h:   2 -
+		i:  72 *
+		j:   2 *
+		k: 120 *
+		l:  15 *
+	`)
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	id, err := KenKenToKeen(p)
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	t.Logf("%s", id)
+	p, err = KeenToKenKen(id)
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	if err := p.DoConstraints(); err != nil {
+		t.Fatalf("Error during DoConstraints: %s", err.Error())
+	}
+	if !p.IsSolved() {
+		t.Errorf("Not solved")
+	}
+}
+
+// TestTathamGameIDs reads game IDs that were decoded by hand rather
+// than written by SudokuToSolo and KenKenToKeen, with parameters that
+// they don't write.
+func TestTathamGameIDs(t *testing.T) {
+	// "r2du" are the symmetry and difficulty that solo generated the
+	// puzzle with.  1 is the first cell, z skips 26 cells, 5 is cell
+	// 28, e skips 5, 9 is cell 34 and z and u skip the last 47.
+	p, err := SoloToSudoku("3x3r2du:1z5e9zu")
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	expect, err := TextToSudoku(`
+		1--------
+		---------
+		---------
+		5-----9--
+		---------
+		---------
+		---------
+		---------
+		---------
+	`)
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	checkSamePuzzle(t, "solo", expect, p)
+
+	// "dh" is keen's difficulty.  The block structure has, for each
+	// of the 24 internal grid lines, vertical ones first, a letter for
+	// the lines without a cage boundary before each boundary, with _6
+	// for six boundaries in a row.
+	p, err = KeenToKenKen("4dh:a_6baa_aa_a__,a3s2d2m6a5m12a7s1")
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	expect, err = TextToKenKen(`
+		aabc
+		debc
+		deff
+		gghh
+
+		a:  3 +
+		b:  2 -
+		c:  2 /
+		d:  6 *
+		e:  5 +
+		f: 12 *
+		g:  7 +
+		h:  1 -
+	`)
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	checkSamePuzzle(t, "keen", expect, p)
+}
+
+func TestKeenLongRuns(t *testing.T) {
+	// Each row is a cage, so there are 30 internal lines in a row
+	// without a cage boundary, more than 'z' can say.
+	grid := "aaaaaa\nbbbbbb\ncccccc\ndddddd\neeeeee\nffffff\n\n"
+	for _, c := range "abcdef" {
+		grid += fmt.Sprintf("%c: 21 +\n", c)
+	}
+	expect, err := TextToKenKen(grid)
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	id := "6:ze_30,a21a21a21a21a21a21"
+	written, err := KenKenToKeen(expect)
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	if written != id {
+		t.Errorf("KenKenToKeen: want %s, got %s", id, written)
+	}
+	p, err := KeenToKenKen(written)
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	checkSamePuzzle(t, "keen", expect, p)
+}
+
+func TestTathamErrorPositions(t *testing.T) {
+	for _, test := range []struct {
+		id     string
+		read   func(string) (*base.Puzzle, error)
+		expect string
+	}{
+		{"3x3:1a1", SoloToSudoku, "character 7: 1 is already given at (1, 1) in row1"},
+		{"3x3:a0", SoloToSudoku, "character 6: value 0 out of range"},
+		{"4:a_6baa_aa_a__,a3s2x2", KeenToKenKen, "character 21: invalid operation 'x' in keen clues"},
+		{"4:a_6b?", KeenToKenKen, "character 7: invalid character '?' in keen block structure"},
+		{"4:a_6baa_aa_a__,a3s2d2m6a5m12a7s9", KeenToKenKen, "line 1, cage h: no combination of values can reach Subtraction 9"},
+	} {
+		_, err := test.read(test.id)
+		if err == nil || err.Error() != test.expect {
+			t.Errorf("Wrong error for %q: want %q, got %v", test.id, test.expect, err)
+		}
+	}
+}
@@ -9,15 +9,22 @@ import "regexp"
 import "strconv"
 import "strings"
 import "unicode"
-import "unicode/utf8"
 import "sudoku/base"
 
 // TextToSudoku returns an unsolved puzzle representing the specified sudoku.
 // The string argument should be a string of digits representing the given
 // values and dashes representing empty cells.  Spaces and tabs are ignored.
-// Newlines represent breaks between rows.
+// Newlines represent breaks between rows.  The grid can be preceded by
+// a metadata header as read by ReadMetadata and followed by a solution
+// section as read by ReadSolution.
 func TextToSudoku(text string) (*base.Puzzle, error) {
+	solution, text, err := ReadSolution(text)
+	if err != nil {
+		return nil, err
+	}
+	metadata, text := ReadMetadata(text)
 	p := base.NewEmptySudoku()
+	p.Metadata = metadata
 
 	linenumber := 1
 	linecharnumber := 0
@@ -26,7 +33,7 @@ func TextToSudoku(text string) (*base.Puzzle, error) {
 	any := false
 	comment := false
 
-	new_line := func () {
+	new_line := func() {
 		if any {
 			row += 1
 			column = 1
@@ -73,7 +80,7 @@ func TextToSudoku(text string) (*base.Puzzle, error) {
 		}
 	}
 
-	return p, nil
+	return p, setSolution(p, solution)
 }
 
 func max(ints ...int) int {
@@ -86,52 +93,36 @@ func max(ints ...int) int {
 	return m
 }
 
+// CageConstraintRegexp matches a cage rule of a KenKen.  The operator
+// can be omitted for a cage with only one cell.  The rule can be
+// followed by a comment, which starts with #.
 var CageConstraintRegexp = regexp.MustCompile(
-	"[ \t]*(?P<group>[a-zA-Z])[ \t]*:[ \t]*(?P<value>[0-9]+)[ \t]*(?P<op>[-+*/])")
+	"^[ \t]*(?P<group>[a-zA-Z])[ \t]*:[ \t]*(?P<value>[0-9]+)[ \t]*(?P<op>[-+*/])?[ \t]*(?:#.*)?[\r\n]*$")
 
 // TextToKenKen makes a ken-ken puzzle from a text specification.
 // The specification starts with a grid of letters, digits and hyphens.
 // Cells identified by the same letter are in the same ken-ken cage.
 // Cells marked with a digit contain that fixed value.
 // Cells marked with a hyphen are not in any cage.
+// The grid can be preceded by a metadata header as read by ReadMetadata.
+// The rules can be followed by a solution section as read by
+// ReadSolution.
 // After the grid description are the rules for each cage identifying
-// the operator and resulting value.
+// the operator and resulting value.  The operator can be left out of
+// the rule for a cage of only one cell, and a rule can be followed by a
+// # comment.
+// If the specification has problems then the error is a KenKenErrors
+// which describes each of them.
 func TextToKenKen(text string) (*base.Puzzle, error) {
-	p := &base.Puzzle{
-		Grid: make(map[base.GridKey]*base.Cell),
-		// Groups: make([]*Group, 0),
+	solution, text, err := ReadSolution(text)
+	if err != nil {
+		return nil, err
 	}
-	groups := make(map[rune]*base.Group)
-	cell_values := make(map[*base.Cell]int)
-	size := 0
+	metadata, text := ReadMetadata(text)
+	b := newKenKenBuilder()
+	b.puzzle.Metadata = metadata
 	last_cell_row := 0
 
-	cell := func(x, y int) *base.Cell {
-		key := base.MakeGridKey(x, y)
-		c := p.Grid[key]
-		if c == nil {
-			c = &base.Cell{
-				X:      x,
-				Y:      y,
-				Puzzle: p,
-			}
-			p.Grid[key] = c
-		}
-		size = max(size, x, y)
-		last_cell_row = y
-		return c
-	}
-
-	group := func(c rune) *base.Group {
-		g := groups[c]
-		if g == nil {
-			g = base.NewGroup(p)
-			groups[c] = g
-			p.Groups = append(p.Groups, g)
-		}
-		return g
-	}
-
 	// First read the grid.
 	linenumber := 1
 	linecharnumber := 0
@@ -140,7 +131,7 @@ func TextToKenKen(text string) (*base.Puzzle, error) {
 	any := false
 	comment := false
 
-	new_line := func () {
+	new_line := func() {
 		if any {
 			row += 1
 			column = 1
@@ -151,6 +142,7 @@ func TextToKenKen(text string) (*base.Puzzle, error) {
 
 	next_cell := func() {
 		any = true
+		last_cell_row = row
 		column += 1
 	}
 
@@ -158,9 +150,13 @@ func TextToKenKen(text string) (*base.Puzzle, error) {
 
 	for {
 		c, _, err := reader.ReadRune()
+		if err == io.EOF {
+			break
+		}
 		if err != nil {
-			return p, err
+			return b.puzzle, err
 		}
+		linecharnumber += 1
 		if comment {
 			if c == '\n' {
 				comment = false
@@ -176,84 +172,58 @@ func TextToKenKen(text string) (*base.Puzzle, error) {
 			// Ignore
 			break
 		case '\n':
-			if any {
-				row += 1
-				column = 1
-			}
+			new_line()
 			// Two empty lines means the grid is done.
 			if row-last_cell_row >= 2 {
 				goto grid_done
 			}
-
 			break
 		case '1', '2', '3', '4', '5', '6', '7', '8', '9':
-			value := int(c - '0')
-			cell_values[cell(column, row)] = value
-			size = max(size, value)
+			b.addGiven(int(c-'0'), column, row, linenumber)
 			next_cell()
 			break
 		case '-':
-			cell(column, row)
+			b.addEmpty(column, row, linenumber)
 			next_cell()
 			break
 		default:
 			if !unicode.IsLetter(c) {
-				return p, fmt.Errorf("invalid input character '%c': line %d, character %d",
-					c, linenumber, linecharnumber)
+				return b.puzzle, KenKenErrors{&KenKenError{
+					Line:  linenumber,
+					Issue: fmt.Sprintf("invalid input character '%c' at character %d", c, linecharnumber),
+				}}
 			}
-			cl := cell(column, row)
-			g := group(c)
-			g.AddCell(cl)
+			b.addToCage(string(c), column, row, linenumber)
 			next_cell()
 			break
 		}
 	}
 grid_done:
 
-	p.Size = size
-	p.Universe = base.Universe(size)
-	for _, c := range p.Grid {
-		c.Possibilities = p.Universe
-	}
-
-	p.AddLineGroups()
-
-	for c, v := range cell_values {
-		c.MustBe(v, base.Given, nil)
-	}
-
 	// Now read the cage constraints.
 	for {
 		s, err := reader.ReadString('\n')
-		if err != nil {
-			if err == io.EOF {
-				return p, nil
-			}
-			return p, err
-		}
-		m := CageConstraintRegexp.FindStringSubmatch(s)
-		if m == nil {
-			continue
+		if err != nil && err != io.EOF {
+			return b.puzzle, err
 		}
-		group_identifier := m[1]
-		value_str := m[2]
-		value, err := strconv.Atoi(value_str)
-		if err != nil {
-			return p, err
-		}
-		op_str := m[3]
-		op := base.KenKenOperatorSymbols[op_str]
-		if op == nil {
-			return p, fmt.Errorf("unsupported cage operator symbol %s", op_str)
+		if strings.TrimSpace(s) != "" {
+			if m := CageConstraintRegexp.FindStringSubmatch(s); m == nil {
+				b.report(linenumber, "", "can't read cage rule %q", strings.TrimSpace(s))
+			} else if value, err := strconv.Atoi(m[2]); err != nil {
+				b.report(linenumber, m[1], "bad cage value %s: %s", m[2], err)
+			} else {
+				b.addRule(linenumber, m[1], value, base.KenKenOperatorSymbols[m[3]])
+			}
 		}
-
-		gi, _ := utf8.DecodeRuneInString(group_identifier)
-		group := groups[gi]
-		if group == nil {
-			return p, fmt.Errorf("There's no group for copnstraint %s", group_identifier)
+		if err == io.EOF {
+			break
 		}
-		group.AddConstraint(base.MakeKenKenConstraint([]*base.KenKenOperator{op}, value))
+		linenumber += 1
 	}
 
-	return p, nil
+	p, err := b.build()
+	if err == nil {
+		err = setSolution(p, solution)
+	}
+	return p, err
 }
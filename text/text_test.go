@@ -2,6 +2,8 @@ package text
 
 import "testing"
 import "bytes"
+import "fmt"
+import "time"
 
 func TestSudokuOnly17Given(t *testing.T) {
 	p, err := TextToSudoku(`
@@ -45,7 +47,6 @@ func TestSudokuOnly17Given(t *testing.T) {
 	}
 }
 
-
 func TestDiscoParty(t *testing.T) {
 	// This test is from the "Disco Party" Ken-Ken published on
 	// page 10 of MIT's student newspaper The Tech on 2017-09-21.
@@ -106,3 +107,202 @@ func TestDiscoParty(t *testing.T) {
 	}
 }
 
+func TestKenKenSingleCellCage(t *testing.T) {
+	p, err := TextToKenKen(`
+		aab
+		cdb
+		edd
+
+		a: 3 +
+		b: 3 /  # a note
+		c: 3    # another
+		d: 7 +
+		e: 1
+	`)
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	if err := p.DoConstraints(); err != nil {
+		t.Fatalf("Error during DoConstraints: %s", err.Error())
+	}
+	if b, v := p.Cell(1, 2).IsSolved(); !b || v != 3 {
+		t.Errorf("Single cell cage wasn't solved: %03o", p.Cell(1, 2).Possibilities)
+	}
+}
+
+func TestKenKenValidation(t *testing.T) {
+	_, err := TextToKenKen(`
+		aabc
+		dbbc
+		deec
+		faag
+
+		a: 8 +
+		b: 7 *
+		d: 3 -
+		e: 7 +
+		f: 5
+		g: 3
+		h: 2 -
+		this isn't a rule
+	`)
+	errs, ok := err.(KenKenErrors)
+	if !ok {
+		t.Fatalf("Expected KenKenErrors, got %#v", err)
+	}
+	for _, e := range errs {
+		t.Logf("%s", e)
+	}
+	expect := []struct {
+		line int
+		cage string
+	}{
+		{14, ""},  // the unreadable rule
+		{13, "h"}, // no such cage
+		{2, "a"},  // not contiguous
+		{8, "b"},  // unreachable
+		{2, "c"},  // no rule
+		{11, "f"}, // single cell out of range
+	}
+	if len(errs) != len(expect) {
+		t.Fatalf("Expected %d errors, got %d", len(expect), len(errs))
+	}
+	for i, e := range expect {
+		if errs[i].Line != e.line || errs[i].Cage != e.cage {
+			t.Errorf("Error %d: want line %d cage %q, got %s", i, e.line, e.cage, errs[i])
+		}
+	}
+}
+
+func TestKenKenLargeCage(t *testing.T) {
+	grid := `
+		aaaabcdef
+		aaaabcdef
+		aaaabcdef
+		ghijklmno
+		ghijklmno
+		ghijklmno
+		pqrstuvwx
+		pqrstuvwx
+		pqrstuvwx
+
+	`
+	rules := ""
+	for _, c := range "bcdefghijklmnopqrstuvwx" {
+		rules += fmt.Sprintf("%c: 6 +\n", c)
+	}
+	// No 12 cells add up to 1 or to 200, multiply to 7^13 or differ by
+	// 200, however long it would take to try every combination.
+	for _, rule := range []string{"a: 1 +", "a: 200 +", "a: 96889010407 *", "a: 200 -"} {
+		start := time.Now()
+		_, err := TextToKenKen(grid + rule + "\n" + rules)
+		if elapsed := time.Since(start); elapsed > 10*time.Second {
+			t.Errorf("%s took %s", rule, elapsed)
+		}
+		errs, ok := err.(KenKenErrors)
+		if !ok || len(errs) != 1 || errs[0].Cage != "a" {
+			t.Errorf("%s: expected an unreachable cage a, got %v", rule, err)
+		}
+	}
+	if _, err := TextToKenKen(grid + "a: 54 +\n" + rules); err != nil {
+		t.Errorf("%s", err)
+	}
+	// Trying every way of dividing 12 values is too slow, so reading
+	// gives up on telling whether this can be reached.
+	start := time.Now()
+	TextToKenKen(grid + "a: 1000000 /\n" + rules)
+	if elapsed := time.Since(start); elapsed > 10*time.Second {
+		t.Errorf("Division took %s", elapsed)
+	}
+}
+
+func TestLongKenKen(t *testing.T) {
+	// The Disco Party puzzle again, but with longer cage names.
+	p, err := TextToLongKenKen(`
+		# The "Disco Party" Ken-Ken from The Tech, 2017-09-21.
+		a1  b1  c1  c1  d1  d1
+		a1  b1  c1  c1  e1  e1
+		a1  f1  f1  c1  g1  g1
+		5   f1  f1  h1  g1  1
+		# Comments can appear in the grid
+		i1  i1  j1  h1  k1  k1
+		i1  1   j1  l1  l1  k1
+
+		a1:  12 *   # and among the rules
+		b1:  20 *
+		c1:  23 +
+		d1:   5 +
+		e1:  12 *
+		f1:  72 *
+		g1:  12 *
+		h1:   2 -
+		i1:  72 *
+		j1:   2 *
+		k1: 120 *
+		l1:  15 *
+	`)
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	for _, err := range p.CheckIntegrity() {
+		t.Errorf("%s", err)
+	}
+	if err := p.DoConstraints(); err != nil {
+		t.Fatalf("Error during DoConstraints: %s", err.Error())
+	}
+	if !p.IsSolved() {
+		t.Errorf("Not solved")
+	}
+}
+
+func TestLongKenKenManyCages(t *testing.T) {
+	// Every cell is its own cage, so there are more cages than letters.
+	size := 9
+	grid := ""
+	rules := ""
+	for y := 1; y <= size; y++ {
+		for x := 1; x <= size; x++ {
+			id := fmt.Sprintf("r%dc%d", y, x)
+			grid += " " + id
+			rules += fmt.Sprintf("%s: %d\n", id, (x+y)%size+1)
+		}
+		grid += "\n"
+	}
+	p, err := TextToLongKenKen(grid + "\n" + rules)
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	if err := p.DoConstraints(); err != nil {
+		t.Fatalf("Error during DoConstraints: %s", err.Error())
+	}
+	if !p.IsSolved() {
+		t.Errorf("Not solved")
+	}
+}
+
+func TestLongKenKenErrors(t *testing.T) {
+	_, err := TextToLongKenKen(`
+		one one two
+		three four two
+		three four four
+
+		one: 3 +    # fine
+		two: 3 /
+		three: 3
+		four: 7 +
+		five: 1
+	`)
+	errs, ok := err.(KenKenErrors)
+	if !ok {
+		t.Fatalf("Expected KenKenErrors, got %#v", err)
+	}
+	if len(errs) != 2 {
+		t.Fatalf("Expected 2 errors, got %s", errs)
+	}
+	if errs[0].Line != 10 || errs[0].Cage != "five" {
+		t.Errorf("Wrong error for the unknown cage: %s", errs[0])
+	}
+	if errs[1].Line != 8 || errs[1].Cage != "three" {
+		t.Errorf("Wrong error for the cage of two cells: %s", errs[1])
+	}
+}
@@ -0,0 +1,259 @@
+package text
+
+import "fmt"
+import "sort"
+import "strings"
+import "sudoku/base"
+
+// WriteOption selects optional content for SudokuToText and
+// KenKenToText to write.
+type WriteOption int
+
+const (
+	// WriteSolution writes the values of the solved cells as a comment
+	// block.
+	WriteSolution WriteOption = iota
+	// WriteCandidates writes the possible values of each cell as a
+	// comment block.
+	WriteCandidates
+)
+
+func hasOption(options []WriteOption, option WriteOption) bool {
+	for _, o := range options {
+		if o == option {
+			return true
+		}
+	}
+	return false
+}
+
+// SudokuToText writes the Metadata, givens and Solution of a sudoku in
+// the format read by TextToSudoku.
+func SudokuToText(p *base.Puzzle, options ...WriteOption) (string, error) {
+	if p.Size != 9 {
+		return "", fmt.Errorf("a sudoku must have 9 rows and columns, not %d", p.Size)
+	}
+	var b strings.Builder
+	writeMetadata(&b, p)
+	writeComments(&b, p, options)
+	for y := 1; y <= p.Size; y++ {
+		for x := 1; x <= p.Size; x++ {
+			if given, v := p.Cell(x, y).IsGiven(); given {
+				fmt.Fprintf(&b, "%d", v)
+			} else {
+				b.WriteString("-")
+			}
+		}
+		b.WriteString("\n")
+	}
+	writeSolution(&b, p)
+	return b.String(), nil
+}
+
+// CageLetters are the single character cage identifiers that
+// TextToKenKen understands, so they limit the number of cages.
+const CageLetters = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ"
+
+// KenKenToText writes a KenKen, with its Metadata and Solution, in the
+// format read by TextToKenKen.
+// The cage rules are rebuilt from the KenKenCageConstraints of the
+// Puzzle's Groups.  A cage keeps its Group's label as its identifier
+// if that label is a single letter.  It's an error for a cell in a cage
+// to be a given, since the format can only write one or the other.
+func KenKenToText(p *base.Puzzle, options ...WriteOption) (string, error) {
+	cages, err := kenkenCages(p)
+	if err != nil {
+		return "", err
+	}
+	if len(cages) > len(CageLetters) {
+		return "", fmt.Errorf("there are %d cages but only %d cage letters",
+			len(cages), len(CageLetters))
+	}
+	ids := make(map[*base.Group]string)
+	used := make(map[string]bool)
+	for _, g := range cages {
+		if l := g.Label(); len(l) == 1 && strings.Contains(CageLetters, l) && !used[l] {
+			ids[g] = l
+			used[l] = true
+		}
+	}
+	next := 0
+	for _, g := range cages {
+		if ids[g] != "" {
+			continue
+		}
+		for used[CageLetters[next:next+1]] {
+			next += 1
+		}
+		ids[g] = CageLetters[next : next+1]
+		used[ids[g]] = true
+	}
+
+	cell_cage := make(map[*base.Cell]*base.Group)
+	for _, g := range cages {
+		for _, c := range g.Cells() {
+			cell_cage[c] = g
+		}
+	}
+
+	var b strings.Builder
+	writeMetadata(&b, p)
+	writeComments(&b, p, options)
+	for y := 1; y <= p.Size; y++ {
+		for x := 1; x <= p.Size; x++ {
+			c := p.Cell(x, y)
+			given, v := c.IsGiven()
+			if g := cell_cage[c]; g != nil {
+				if given {
+					return "", fmt.Errorf("cell %d, %d of cage %s is given %d, which can't be written",
+						x, y, ids[g], v)
+				}
+				b.WriteString(ids[g])
+			} else if given {
+				fmt.Fprintf(&b, "%d", v)
+			} else {
+				b.WriteString("-")
+			}
+		}
+		b.WriteString("\n")
+	}
+	b.WriteString("\n")
+	for _, g := range cages {
+		rule, err := cageRule(g)
+		if err != nil {
+			return "", err
+		}
+		fmt.Fprintf(&b, "%s: %s\n", ids[g], rule)
+	}
+	writeSolution(&b, p)
+	return b.String(), nil
+}
+
+// kenkenCages returns the Groups of the Puzzle that are KenKen cages,
+// ordered by the position of their first cell.  It's an error for the
+// Puzzle to have any Groups other than cages, rows and columns.
+func kenkenCages(p *base.Puzzle) ([]*base.Group, error) {
+	cages := []*base.Group{}
+	for _, g := range p.Groups {
+		if isCage(g) {
+			cages = append(cages, g)
+		} else if !isLine(p, g) {
+			return nil, fmt.Errorf("group %q is neither a row, a column nor a cage", g.Label())
+		}
+	}
+	first := func(g *base.Group) int {
+		f := p.Size * p.Size
+		for _, c := range g.Cells() {
+			if i := (c.Y-1)*p.Size + c.X - 1; i < f {
+				f = i
+			}
+		}
+		return f
+	}
+	sort.SliceStable(cages, func(i, j int) bool {
+		return first(cages[i]) < first(cages[j])
+	})
+	return cages, nil
+}
+
+// isCage returns true if any of the Group's constraints is a
+// KenKenCageConstraint.
+func isCage(g *base.Group) bool {
+	for _, c := range g.Constraints() {
+		if _, ok := c.(*base.KenKenCageConstraint); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// isLine returns true if the Group is a whole row or column of the
+// Puzzle.
+func isLine(p *base.Puzzle, g *base.Group) bool {
+	cells := g.Cells()
+	if len(cells) != p.Size {
+		return false
+	}
+	row, column := true, true
+	for _, c := range cells {
+		row = row && c.Y == cells[0].Y
+		column = column && c.X == cells[0].X
+	}
+	return row || column
+}
+
+// cageRule returns the value and operator of a cage's rule as written
+// in TextToKenKen's format.
+func cageRule(g *base.Group) (string, error) {
+	rules := []string{}
+	for _, c := range g.Constraints() {
+		kc, ok := c.(*base.KenKenCageConstraint)
+		if !ok {
+			return "", fmt.Errorf("cage %q has an unsupported constraint %s", g.Label(), c.Name())
+		}
+		if len(kc.Operators()) != 1 {
+			return "", fmt.Errorf("cage %q has %d operators", g.Label(), len(kc.Operators()))
+		}
+		op := kc.Operators()[0]
+		if len(g.Cells()) == 1 && op.Symbol == "Addition" {
+			rules = append(rules, fmt.Sprintf("%d", kc.Expect()))
+			continue
+		}
+		symbol := operatorSymbol(op)
+		if symbol == "" {
+			return "", fmt.Errorf("no symbol for the %s operator of cage %q", op.Symbol, g.Label())
+		}
+		rules = append(rules, fmt.Sprintf("%d %s", kc.Expect(), symbol))
+	}
+	if len(rules) != 1 {
+		return "", fmt.Errorf("cage %q has %d rules", g.Label(), len(rules))
+	}
+	return rules[0], nil
+}
+
+// operatorSymbol returns the symbol that identifies the KenKenOperator
+// in a cage rule.
+func operatorSymbol(op *base.KenKenOperator) string {
+	for symbol, o := range base.KenKenOperatorSymbols {
+		if o.Symbol == op.Symbol {
+			return symbol
+		}
+	}
+	return ""
+}
+
+// writeComments writes the optional comment blocks that are selected
+// by options.
+func writeComments(b *strings.Builder, p *base.Puzzle, options []WriteOption) {
+	if hasOption(options, WriteSolution) {
+		b.WriteString("# Solution:\n")
+		for y := 1; y <= p.Size; y++ {
+			b.WriteString("#  ")
+			for x := 1; x <= p.Size; x++ {
+				if solved, v := p.Cell(x, y).IsSolved(); solved {
+					fmt.Fprintf(b, " %d", v)
+				} else {
+					b.WriteString(" -")
+				}
+			}
+			b.WriteString("\n")
+		}
+		b.WriteString("\n")
+	}
+	if hasOption(options, WriteCandidates) {
+		b.WriteString("# Candidates:\n")
+		width := 0
+		for _, c := range p.Grid {
+			width = max(width, c.Possibilities.Len())
+		}
+		for y := 1; y <= p.Size; y++ {
+			line := "#  "
+			for x := 1; x <= p.Size; x++ {
+				line += fmt.Sprintf(" %-*s", width, p.Cell(x, y).Possibilities.String(""))
+			}
+			b.WriteString(strings.TrimRight(line, " "))
+			b.WriteString("\n")
+		}
+		b.WriteString("\n")
+	}
+}
@@ -0,0 +1,166 @@
+package text
+
+import "fmt"
+import "io/ioutil"
+import "path/filepath"
+import "reflect"
+import "sort"
+import "strings"
+import "testing"
+import "sudoku/base"
+
+// describePuzzle returns a description of a Puzzle's cells and groups
+// that's suitable for comparing two Puzzles.
+func describePuzzle(p *base.Puzzle) []string {
+	d := []string{}
+	for y := 1; y <= p.Size; y++ {
+		for x := 1; x <= p.Size; x++ {
+			c := p.Cell(x, y)
+			given, v := c.IsGiven()
+			d = append(d, fmt.Sprintf("cell %d %d %v %d %03o", x, y, given, v, c.Possibilities))
+		}
+	}
+	groups := []string{}
+	for _, g := range p.Groups {
+		cells := []string{}
+		for _, c := range g.Cells() {
+			cells = append(cells, fmt.Sprintf("%d,%d", c.X, c.Y))
+		}
+		sort.Strings(cells)
+		constraints := []string{}
+		for _, c := range g.Constraints() {
+			constraints = append(constraints, c.Name())
+		}
+		groups = append(groups, fmt.Sprintf("group %s: %s",
+			strings.Join(cells, " "), strings.Join(constraints, ", ")))
+	}
+	sort.Strings(groups)
+	return append(d, groups...)
+}
+
+func checkSamePuzzle(t *testing.T, name string, p1, p2 *base.Puzzle) {
+	d1 := describePuzzle(p1)
+	d2 := describePuzzle(p2)
+	if len(d1) != len(d2) {
+		t.Errorf("%s: puzzles differ in size: %d, %d", name, len(d1), len(d2))
+		return
+	}
+	for i := range d1 {
+		if d1[i] != d2[i] {
+			t.Errorf("%s: puzzles differ:\n  %s\n  %s", name, d1[i], d2[i])
+		}
+	}
+}
+
+// unreadableExamples are the example files that TestRoundTripExamples
+// expects not to be read, because they use _ for an empty cell.
+var unreadableExamples = map[string]bool{
+	"made_up_sudoku_1.txt": true,
+	"made_up_sudoku_2.txt": true,
+}
+
+func TestRoundTripExamples(t *testing.T) {
+	files, err := filepath.Glob("../text_application/examples/*.txt")
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	for _, file := range files {
+		name := filepath.Base(file)
+		bytes, err := ioutil.ReadFile(file)
+		if err != nil {
+			t.Fatalf("%s", err)
+		}
+		parse := TextToSudoku
+		write := SudokuToText
+		if strings.Contains(name, "kenken") {
+			parse = TextToKenKen
+			write = KenKenToText
+		}
+		p1, err := parse(string(bytes))
+		if unreadableExamples[name] {
+			if err == nil {
+				t.Errorf("%s: was expected not to be read", name)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: can't be read: %s", name, err)
+			continue
+		}
+		written, err := write(p1)
+		if err != nil {
+			t.Errorf("%s: %s", name, err)
+			continue
+		}
+		p2, err := parse(written)
+		if err != nil {
+			t.Errorf("%s: can't read back\n%s\n%s", name, written, err)
+			continue
+		}
+		checkSamePuzzle(t, name, p1, p2)
+		if !reflect.DeepEqual(p1.Solution, p2.Solution) {
+			t.Errorf("%s: the solution wasn't written\n%s", name, written)
+		}
+	}
+}
+
+func TestWriteOptions(t *testing.T) {
+	p, err := TextToKenKen(`
+		aab
+		cdb
+		edd
+
+		a: 3 +
+		b: 3 /
+		c: 3
+		d: 7 +
+		e: 1
+	`)
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	if err := p.DoConstraints(); err != nil {
+		t.Fatalf("Error during DoConstraints: %s", err.Error())
+	}
+	written, err := KenKenToText(p, WriteSolution, WriteCandidates)
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	t.Logf("%s", written)
+	if !strings.Contains(written, "#   2 1 3\n#   3 2 1\n#   1 3 2\n") {
+		t.Errorf("Solution wasn't written")
+	}
+	if !strings.Contains(written, "# Candidates:\n") {
+		t.Errorf("Candidates weren't written")
+	}
+	p2, err := TextToKenKen(written)
+	if err != nil {
+		t.Fatalf("Can't read back\n%s\n%s", written, err)
+	}
+	if err := p2.DoConstraints(); err != nil {
+		t.Fatalf("Error during DoConstraints: %s", err.Error())
+	}
+	checkSamePuzzle(t, "options", p, p2)
+}
+
+func TestWriteCagedGiven(t *testing.T) {
+	p, err := TextToKenKen(`
+		aab
+		cdb
+		edd
+
+		a: 3 +
+		b: 3 /
+		c: 3
+		d: 7 +
+		e: 1
+	`)
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	p.Cell(1, 1).MustBe(2, base.Given, nil)
+	_, err = KenKenToText(p)
+	if err == nil || err.Error() != "cell 1, 1 of cage a is given 2, which can't be written" {
+		t.Errorf("Expected an error for a given in a cage, got %v", err)
+	}
+}
@@ -0,0 +1,194 @@
+package main
+
+import "sudoku/base"
+import "sudoku/text"
+import "fmt"
+import "io"
+import "io/ioutil"
+import "os"
+import "path/filepath"
+import "sort"
+import "strings"
+import "sync"
+import "text/tabwriter"
+import "time"
+
+// batch_job is one puzzle of a batch and the result of solving it.
+type batch_job struct {
+	name        string
+	puzzle_type string
+	puzzle      *base.Puzzle
+	// err is why the puzzle couldn't be read or solved.
+	err     error
+	solved  bool
+	elapsed time.Duration
+	guesses int
+	// The ValueCount metrics, as in the Progress line of solve.
+	max_value_count       int
+	pre_solve_value_count int
+	value_count           int
+	solved_value_count    int
+}
+
+// batch_files expands the command line arguments of a batch into the
+// files to read.  An argument can be a file, a directory, whose files
+// are all read, or a glob pattern.
+func batch_files(args []string) ([]string, []*batch_job) {
+	files := []string{}
+	failures := []*batch_job{}
+	for _, arg := range args {
+		if strings.ContainsAny(arg, "*?[") {
+			matches, err := filepath.Glob(arg)
+			if err == nil && len(matches) == 0 {
+				err = fmt.Errorf("no files match")
+			}
+			if err != nil {
+				failures = append(failures, &batch_job{name: arg, err: err})
+			}
+			files = append(files, matches...)
+			continue
+		}
+		info, err := os.Stat(arg)
+		if err != nil {
+			failures = append(failures, &batch_job{name: arg, err: err})
+			continue
+		}
+		if !info.IsDir() {
+			files = append(files, arg)
+			continue
+		}
+		entries, err := ioutil.ReadDir(arg)
+		if err != nil {
+			failures = append(failures, &batch_job{name: arg, err: err})
+			continue
+		}
+		for _, entry := range entries {
+			if entry.Mode().IsRegular() && !strings.HasPrefix(entry.Name(), ".") {
+				files = append(files, filepath.Join(arg, entry.Name()))
+			}
+		}
+	}
+	return files, failures
+}
+
+// batch_read reads the puzzles of each file, making a batch_job for
+// each of them.  If pt is nil the type of each file's puzzles is
+// detected.
+func batch_read(pt *PuzzleType, files []string) []*batch_job {
+	jobs := []*batch_job{}
+	for _, file := range files {
+		bytes, err := ioutil.ReadFile(file)
+		if err != nil {
+			jobs = append(jobs, &batch_job{name: file, err: err})
+			continue
+		}
+		file_type, puzzles, err := parse_puzzles(pt, file, string(bytes), nil)
+		if _, ok := err.(text.UnsupportedConstraints); ok {
+			err = nil
+		}
+		if err != nil {
+			jobs = append(jobs, &batch_job{name: file, err: err})
+			continue
+		}
+		for i, puzzle := range puzzles {
+			name := file
+			if len(puzzles) > 1 {
+				name = fmt.Sprintf("%s #%d", file, i+1)
+			}
+			jobs = append(jobs, &batch_job{name: name, puzzle_type: file_type.Name, puzzle: puzzle})
+		}
+	}
+	return jobs
+}
+
+// run solves the job's puzzle and records the results.
+func (job *batch_job) run(search search_options) {
+	p := job.puzzle
+	job.max_value_count = p.MaxValueCount()
+	job.pre_solve_value_count = p.ValueCount()
+	start := time.Now()
+	p, job.err = search.solve(p)
+	job.elapsed = time.Since(start)
+	job.solved = job.err == nil && p.IsSolved()
+	job.guesses = p.SearchStats.Guesses
+	job.value_count = p.ValueCount()
+	job.solved_value_count = p.SolvedValueCount()
+}
+
+func (job *batch_job) status() string {
+	switch {
+	case job.puzzle == nil:
+		return "unreadable"
+	case job.solved:
+		return "solved"
+	case job.err != nil:
+		return "failed"
+	}
+	return "unsolved"
+}
+
+// batch_solve solves the puzzles of the files, directories and glob
+// patterns in args using a pool of workers and writes a summary table
+// to out.  It returns false if any puzzle wasn't solved.
+func batch_solve(out io.Writer, pt *PuzzleType, args []string, workers int, search search_options) bool {
+	files, failures := batch_files(args)
+	sort.Strings(files)
+	jobs := append(failures, batch_read(pt, files)...)
+
+	queue := make(chan *batch_job)
+	var wg sync.WaitGroup
+	if workers < 1 {
+		workers = 1
+	}
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for job := range queue {
+				job.run(search)
+			}
+		}()
+	}
+	for _, job := range jobs {
+		if job.puzzle != nil {
+			queue <- job
+		}
+	}
+	close(queue)
+	wg.Wait()
+
+	ok := true
+	solved := 0
+	w := tabwriter.NewWriter(out, 0, 8, 2, ' ', 0)
+	fmt.Fprintf(w, "Puzzle\tType\tStatus\tTime\tGuesses\tMax\tBefore\tAfter\tSolved\t\n")
+	for _, job := range jobs {
+		if job.solved {
+			solved += 1
+		} else {
+			ok = false
+		}
+		if job.puzzle == nil {
+			fmt.Fprintf(w, "%s\t\t%s\t\t\t\t\t\t\t\n", job.name, job.status())
+			continue
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%d\t%d\t%d\t%d\t%d\t\n",
+			job.name, job.puzzle_type, job.status(), job.elapsed.Round(time.Microsecond),
+			job.guesses, job.max_value_count, job.pre_solve_value_count,
+			job.value_count, job.solved_value_count)
+	}
+	w.Flush()
+	fmt.Fprintf(out, "\n%d of %d puzzles solved\n", solved, len(jobs))
+	for _, job := range jobs {
+		if job.err == nil {
+			continue
+		}
+		// Only the first of several problems.
+		lines := strings.Split(job.err.Error(), "\n")
+		if len(lines) > 1 {
+			fmt.Fprintf(out, "%s: %s (and %d more problems)\n", job.name, lines[0], len(lines)-1)
+		} else {
+			fmt.Fprintf(out, "%s: %s\n", job.name, lines[0])
+		}
+	}
+	return ok
+}
@@ -0,0 +1,82 @@
+package main
+
+import "sudoku/base"
+import "sudoku/dlx"
+import "flag"
+import "fmt"
+import "io"
+import "os"
+
+func check_command(flags *flag.FlagSet, args []string) bool {
+	pf := add_puzzle_flags(flags)
+	var exact_cover bool
+	flags.BoolVar(&exact_cover, "dlx", false,
+		"Count solutions as an exact cover problem, which is faster, for puzzles whose groups only require different values.")
+	flags.Parse(args)
+	_, puzzles, err := pf.read()
+	if err != nil {
+		fail(err)
+	}
+	ok := true
+	for i, puzzle := range puzzles {
+		if !check_puzzle(os.Stdout, puzzle_name(i, puzzles), puzzle, exact_cover) {
+			ok = false
+		}
+	}
+	return ok
+}
+
+// check_puzzle reports whether the puzzle's givens contradict each
+// other, how many solutions it has and, if it has a solution section,
+// whether that's the solution.  It returns false unless the puzzle is
+// valid with a unique solution.  If exact_cover is true the solutions
+// are counted by the dlx package.
+func check_puzzle(out io.Writer, name string, puzzle *base.Puzzle, exact_cover bool) bool {
+	if err := puzzle.Clone().DoConstraints(); err != nil {
+		fmt.Fprintf(out, "%s: invalid, it has no solution: %s\n", name, err)
+		return false
+	}
+	count := 0
+	if exact_cover {
+		var err error
+		count, err = dlx.CountSolutions(puzzle, 2)
+		if err != nil {
+			fmt.Fprintf(out, "%s: %s\n", name, err)
+			return false
+		}
+	} else {
+		count = puzzle.CountSolutions(2)
+	}
+	switch count {
+	case 0:
+		fmt.Fprintf(out, "%s: valid but has no solution\n", name)
+		return false
+	case 1:
+	default:
+		fmt.Fprintf(out, "%s: valid but has more than one solution\n", name)
+		return false
+	}
+	if puzzle.Solution == nil {
+		fmt.Fprintf(out, "%s: valid with a unique solution\n", name)
+		return true
+	}
+	solved := puzzle.Clone()
+	if err := solved.GuessSolve(); err != nil {
+		fmt.Fprintf(out, "%s: %s\n", name, err)
+		return false
+	}
+	mismatches, err := solved.Verify()
+	if err != nil {
+		fmt.Fprintf(out, "%s: %s\n", name, err)
+		return false
+	}
+	if len(mismatches) > 0 {
+		fmt.Fprintf(out, "%s: valid with a unique solution that isn't its solution section:\n", name)
+		for _, m := range mismatches {
+			fmt.Fprintf(out, "  %s\n", m)
+		}
+		return false
+	}
+	fmt.Fprintf(out, "%s: valid with a unique solution that matches its solution section\n", name)
+	return true
+}
@@ -0,0 +1,53 @@
+package main
+
+import "flag"
+import "fmt"
+import "strings"
+
+func convert_command(flags *flag.FlagSet, args []string) bool {
+	var output string
+	var to PuzzleTypeVar
+	pf := add_puzzle_flags(flags)
+	flags.StringVar(&output, "output", "-", "The file to write the converted puzzles to.")
+	flags.Var(&to, "to",
+		fmt.Sprintf("The type of puzzle text to write, one of %s.",
+			strings.Join(writer_names(), ", ")))
+	flags.Parse(args)
+	if to.Value == nil || to.Value.Writer == nil {
+		fail(fmt.Errorf("-to must be one of %s", strings.Join(writer_names(), ", ")))
+	}
+	_, puzzles, err := pf.read()
+	if err != nil {
+		fail(err)
+	}
+	out, err := open_output(output)
+	if err != nil {
+		fail(err)
+	}
+	if output != "-" {
+		defer out.Close()
+	}
+	for i, puzzle := range puzzles {
+		written, err := to.Value.Writer(puzzle)
+		if err != nil {
+			fail(fmt.Errorf("%s: %s", puzzle_name(i, puzzles), err))
+		}
+		if !strings.HasSuffix(written, "\n") {
+			written += "\n"
+		}
+		out.WriteString(written)
+	}
+	return true
+}
+
+// writer_names returns the names of the puzzle types that can be
+// written.
+func writer_names() []string {
+	names := []string{}
+	for _, pt := range PuzzleTypes {
+		if pt.Writer != nil {
+			names = append(names, pt.Name)
+		}
+	}
+	return names
+}
@@ -0,0 +1,107 @@
+package main
+
+import "sudoku/base"
+import "sudoku/text"
+import "fmt"
+import "io"
+import "regexp"
+import "strings"
+
+var solo_regexp = regexp.MustCompile("^[0-9]+x[0-9]+[a-z]*:[^ \t\n]*$")
+var keen_regexp = regexp.MustCompile("^[0-9]+[a-z]*:[^ \t\n]*,[^ \t\n]*$")
+var digit_row_regexp = regexp.MustCompile("^[-1-9]{9}$")
+
+// detect_puzzle_type chooses the type of the puzzle in input and says
+// why.  A cage rule section means a KenKen, nine rows of nine digits
+// mean a sudoku and otherwise a variant: metadata header that names a
+// puzzle type decides.  Failing that the input is recognized by its
+// format.
+func detect_puzzle_type(input string) (*PuzzleType, string, error) {
+	_, body, err := text.ReadSolution(input)
+	if err != nil {
+		body = input
+	}
+	metadata, body := text.ReadMetadata(body)
+	lines := []string{}
+	for _, line := range strings.Split(body, "\n") {
+		if i := strings.IndexRune(line, '#'); i >= 0 {
+			line = line[:i]
+		}
+		if line = strings.TrimSpace(line); line != "" {
+			lines = append(lines, line)
+		}
+	}
+	find := func(name string) *PuzzleType {
+		pt, _ := find_puzzle_type(name)
+		return pt
+	}
+
+	for _, line := range lines {
+		if text.CageConstraintRegexp.MatchString(line) {
+			return find("kenken"), fmt.Sprintf("it has the cage rule %q", line), nil
+		}
+		if text.LongCageConstraintRegexp.MatchString(line) {
+			return find("longkenken"), fmt.Sprintf("it has the cage rule %q, whose cage name is longer than a letter", line), nil
+		}
+	}
+
+	digit_rows := 0
+	for _, line := range lines {
+		if digit_row_regexp.MatchString(strings.Join(strings.Fields(line), "")) {
+			digit_rows += 1
+		}
+	}
+	if digit_rows == 9 && len(lines) == 9 {
+		return find("sudoku"), "it's nine rows of nine digits", nil
+	}
+
+	if variant, found := metadata["variant"]; found {
+		if pt := find(strings.ToLower(variant)); pt != nil {
+			return pt, fmt.Sprintf("its variant: header is %q", variant), nil
+		}
+	}
+
+	if _, err := text.FPuzzlesJSON(input); err == nil {
+		return find("fpuzzles"), "it's f-puzzles data", nil
+	}
+	if len(lines) == 1 && solo_regexp.MatchString(lines[0]) {
+		return find("solo"), "it's a game ID whose parameters are the size of a box", nil
+	}
+	if len(lines) == 1 && keen_regexp.MatchString(lines[0]) {
+		return find("keen"), "it's a game ID of a grid size, cages and their rules", nil
+	}
+	line_puzzles := 0
+	for _, line := range lines {
+		if len(strings.Fields(line)[0]) == 81 {
+			line_puzzles += 1
+		}
+	}
+	if line_puzzles > 0 && line_puzzles == len(lines) {
+		return find("lines"), "each line is a sudoku of 81 characters", nil
+	}
+	if _, err := text.TolerantTextToSudoku(input); err == nil {
+		return find("sudokuart"), "it's a drawing of a sudoku grid", nil
+	}
+	return nil, "", fmt.Errorf("can't tell what type of puzzle this is, -puzzle can say which of %s it is",
+		strings.Join(puzzle_type_names(), ", "))
+}
+
+// parse_puzzles reads the puzzles in input, which are of type pt or, if
+// pt is nil, of the type that detect_puzzle_type chooses.  What type was
+// chosen and why is written to log, prefixed by name.  It returns the
+// type along with the puzzles.
+func parse_puzzles(pt *PuzzleType, name string, input string, log io.Writer) (*PuzzleType, []*base.Puzzle, error) {
+	if pt == nil {
+		var reason string
+		var err error
+		pt, reason, err = detect_puzzle_type(input)
+		if err != nil {
+			return nil, nil, err
+		}
+		if log != nil {
+			fmt.Fprintf(log, "%s: %s puzzle because %s\n", name, pt.Name, reason)
+		}
+	}
+	puzzles, err := pt.ParseAll(input)
+	return pt, puzzles, err
+}
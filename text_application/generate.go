@@ -0,0 +1,136 @@
+package main
+
+import "sudoku/base"
+import "sudoku/generate"
+import "sudoku/text"
+import "flag"
+import "fmt"
+import "io/ioutil"
+import "strings"
+import "time"
+
+func generate_command(flags *flag.FlagSet, args []string) bool {
+	var output string
+	var count int
+	var seed int64
+	var difficulty string
+	var symmetry string
+	var givens int
+	var mask_file string
+	var kind string
+	var size int
+	var daily string
+	var to PuzzleTypeVar
+	flags.StringVar(&output, "output", "-", "The file to write the new puzzles to.")
+	flags.IntVar(&count, "count", 1, "The number of puzzles to make.")
+	flags.Int64Var(&seed, "seed", 0,
+		"The seed of the random numbers, so that the same puzzles can be made again.  0 means a seed based on the time.")
+	flags.StringVar(&difficulty, "difficulty", "",
+		fmt.Sprintf("The difficulty of the puzzles, one of %s.  Any difficulty if not given.",
+			strings.Join(base.Difficulties, ", ")))
+	flags.StringVar(&symmetry, "symmetry", "none",
+		"The symmetry of the givens: none, rotational or mirror.")
+	flags.IntVar(&givens, "givens", 0,
+		"The number of givens to stop removing givens at.  0 means as few as possible.")
+	flags.StringVar(&mask_file, "mask", "",
+		"A file with a grid of x and - marking the cells that the givens must be in.")
+	flags.StringVar(&kind, "puzzle", "sudoku", "The type of puzzle to make: sudoku or kenken.")
+	flags.IntVar(&size, "size", 6, "The number of rows and columns of a KenKen.")
+	flags.StringVar(&daily, "daily", "",
+		fmt.Sprintf("A date, as %s, or any other name of the daily puzzle to make, which is always the same.  -count makes the puzzles of the following days.",
+			generate.DateFormat))
+	to.Set("sudoku")
+	flags.Var(&to, "to",
+		fmt.Sprintf("The type of puzzle text to write, one of %s.",
+			strings.Join(writer_names(), ", ")))
+	flags.Parse(args)
+	if kind != "sudoku" && kind != "kenken" {
+		fail(fmt.Errorf("-puzzle must be sudoku or kenken"))
+	}
+	given := make(map[string]bool)
+	flags.Visit(func(f *flag.Flag) {
+		given[f.Name] = true
+	})
+	if kind == "kenken" {
+		for _, name := range []string{"daily", "mask", "difficulty", "symmetry", "givens"} {
+			if given[name] {
+				fail(fmt.Errorf("-%s only applies to sudokus, not to -puzzle=kenken", name))
+			}
+		}
+		if !given["to"] {
+			to.Set("kenken")
+		}
+	}
+	if to.Value.Writer == nil {
+		fail(fmt.Errorf("-to must be one of %s", strings.Join(writer_names(), ", ")))
+	}
+	sym, err := generate.ParseSymmetry(symmetry)
+	if err != nil {
+		fail(err)
+	}
+	var mask map[base.GridKey]bool
+	if mask_file != "" {
+		bytes, err := ioutil.ReadFile(mask_file)
+		if err != nil {
+			fail(fmt.Errorf("Can't read %s: %s", mask_file, err))
+		}
+		mask, err = text.TextToMask(string(bytes))
+		if err != nil {
+			fail(fmt.Errorf("%s: %s", mask_file, err))
+		}
+	}
+	if seed == 0 {
+		seed = time.Now().UnixNano()
+	}
+	out, err := open_output(output)
+	if err != nil {
+		fail(err)
+	}
+	if output != "-" {
+		defer out.Close()
+	}
+	for i := 0; i < count; i++ {
+		var puzzle *base.Puzzle
+		if daily != "" {
+			puzzle, err = generate.Daily(daily_seed(daily, i))
+		} else if kind == "kenken" {
+			puzzle, _, err = generate.KenKen(generate.KenKenOptions{
+				Size: size,
+				Seed: seed + int64(i),
+			})
+		} else {
+			puzzle, err = generate.Sudoku(generate.Options{
+				Seed:       seed + int64(i),
+				Difficulty: difficulty,
+				Symmetry:   sym,
+				Givens:     givens,
+				Mask:       mask,
+			})
+		}
+		if err != nil {
+			fail(err)
+		}
+		written, err := to.Value.Writer(puzzle)
+		if err != nil {
+			fail(err)
+		}
+		if !strings.HasSuffix(written, "\n") {
+			written += "\n"
+		}
+		out.WriteString(written)
+	}
+	return true
+}
+
+// daily_seed returns the name of the ith daily puzzle from the one
+// named by daily: the ith day after a date, or daily followed by the
+// number for any other name.
+func daily_seed(daily string, i int) string {
+	if i == 0 {
+		return daily
+	}
+	if date, err := time.Parse(generate.DateFormat, daily); err == nil {
+		return date.AddDate(0, 0, i).Format(generate.DateFormat)
+	}
+	return fmt.Sprintf("%s %d", daily, i)
+}
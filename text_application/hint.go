@@ -0,0 +1,43 @@
+package main
+
+import "flag"
+import "fmt"
+
+func hint_command(flags *flag.FlagSet, args []string) bool {
+	var count int
+	pf := add_puzzle_flags(flags)
+	flags.IntVar(&count, "count", 1, "The number of cells to give hints about.")
+	flags.Parse(args)
+	_, puzzles, err := pf.read()
+	if err != nil {
+		fail(err)
+	}
+	ok := true
+	for i, puzzle := range puzzles {
+		if len(puzzles) > 1 {
+			fmt.Printf("%s:\n", puzzle_name(i, puzzles))
+		}
+		for n := 0; n < count; n++ {
+			cell, justifications, err := puzzle.Hint()
+			if err != nil {
+				fmt.Printf("%s\n", err)
+				ok = false
+				break
+			}
+			if cell == nil {
+				if puzzle.IsSolved() {
+					fmt.Printf("The puzzle is solved.\n")
+				} else {
+					fmt.Printf("Nothing more can be deduced without guessing.\n")
+				}
+				break
+			}
+			_, value := cell.IsSolved()
+			fmt.Printf("Cell(%d, %d) must be %d:\n", cell.X, cell.Y, value)
+			for _, j := range justifications {
+				fmt.Printf("  %s\n", j.Pretty())
+			}
+		}
+	}
+	return ok
+}
@@ -0,0 +1,143 @@
+package main
+
+import "sudoku/base"
+import "encoding/json"
+import "io"
+
+// The structures that -format=json writes.
+
+type json_output struct {
+	Puzzles []*json_puzzle `json:"puzzles,omitempty"`
+	// Error is why the input couldn't be read.
+	Error *json_error `json:"error,omitempty"`
+}
+
+type json_puzzle struct {
+	Metadata map[string]string `json:"metadata,omitempty"`
+	Size     int               `json:"size"`
+	Solved   bool              `json:"solved"`
+	// Values has the value of each solved cell, row by row, or 0 if
+	// the cell isn't solved.
+	Values [][]int `json:"values"`
+	// Candidates has the possible values of each cell, row by row.
+	Candidates     [][][]int             `json:"candidates"`
+	Metrics        json_metrics          `json:"metrics"`
+	Justifications []*json_justification `json:"justifications"`
+	// Error is why the puzzle couldn't be solved.
+	Error *json_error `json:"error,omitempty"`
+}
+
+type json_metrics struct {
+	MaxValueCount      int `json:"max_value_count"`
+	PreSolveValueCount int `json:"pre_solve_value_count"`
+	ValueCount         int `json:"value_count"`
+	SolvedValueCount   int `json:"solved_value_count"`
+	// The SearchStats of the guessing.
+	Guesses    int `json:"guesses"`
+	Backtracks int `json:"backtracks"`
+	MaxDepth   int `json:"max_depth"`
+}
+
+type json_justification struct {
+	Tick       uint   `json:"tick"`
+	X          int    `json:"x"`
+	Y          int    `json:"y"`
+	Operation  string `json:"operation"`
+	Value      int    `json:"value"`
+	Constraint string `json:"constraint"`
+	Group      string `json:"group,omitempty"`
+}
+
+// json_error describes an error.  If the error is a Contradiction the
+// cell, group and constraint that it's about are included.
+type json_error struct {
+	Message    string `json:"message"`
+	Type       string `json:"type"`
+	X          int    `json:"x,omitempty"`
+	Y          int    `json:"y,omitempty"`
+	Group      string `json:"group,omitempty"`
+	Constraint string `json:"constraint,omitempty"`
+	Issue      string `json:"issue,omitempty"`
+}
+
+func make_json_error(err error) *json_error {
+	if err == nil {
+		return nil
+	}
+	je := &json_error{Message: err.Error(), Type: "error"}
+	if c, ok := err.(*base.Contradiction); ok {
+		je.Type = "contradiction"
+		je.Issue = c.Issue
+		if c.Cell != nil {
+			je.X = c.Cell.X
+			je.Y = c.Cell.Y
+		}
+		if c.Group != nil {
+			je.Group = c.Group.Label()
+		}
+		if c.Constraint != nil {
+			je.Constraint = c.Constraint.Name()
+		}
+	}
+	return je
+}
+
+// solve_json solves the puzzle and describes the result.
+func solve_json(puzzle *base.Puzzle, search search_options) *json_puzzle {
+	jp := &json_puzzle{
+		Metadata:       puzzle.Metadata,
+		Size:           puzzle.Size,
+		Justifications: []*json_justification{},
+	}
+	jp.Metrics.MaxValueCount = puzzle.MaxValueCount()
+	jp.Metrics.PreSolveValueCount = puzzle.ValueCount()
+	puzzle, err := search.solve(puzzle)
+	jp.Error = make_json_error(err)
+	jp.Solved = err == nil && puzzle.IsSolved()
+	jp.Metrics.ValueCount = puzzle.ValueCount()
+	jp.Metrics.SolvedValueCount = puzzle.SolvedValueCount()
+	jp.Metrics.Guesses = puzzle.SearchStats.Guesses
+	jp.Metrics.Backtracks = puzzle.SearchStats.Backtracks
+	jp.Metrics.MaxDepth = puzzle.SearchStats.MaxDepth
+	for y := 1; y <= puzzle.Size; y++ {
+		values := []int{}
+		candidates := [][]int{}
+		for x := 1; x <= puzzle.Size; x++ {
+			c := puzzle.Cell(x, y)
+			solved, v := c.IsSolved()
+			if !solved {
+				v = 0
+			}
+			values = append(values, v)
+			possible := []int{}
+			c.Possibilities.DoValues(func(v int) bool {
+				possible = append(possible, v)
+				return true
+			})
+			candidates = append(candidates, possible)
+		}
+		jp.Values = append(jp.Values, values)
+		jp.Candidates = append(jp.Candidates, candidates)
+	}
+	for _, j := range puzzle.Justifications {
+		jj := &json_justification{
+			Tick:       j.Tick,
+			X:          j.Cell.X,
+			Y:          j.Cell.Y,
+			Operation:  base.JustificationOpStrings[j.Operation],
+			Value:      j.Value,
+			Constraint: j.Constraint.Name(),
+		}
+		if j.Group != nil {
+			jj.Group = j.Group.Label()
+		}
+		jp.Justifications = append(jp.Justifications, jj)
+	}
+	return jp
+}
+
+func write_json(out io.Writer, output *json_output) error {
+	encoder := json.NewEncoder(out)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(output)
+}
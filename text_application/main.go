@@ -4,27 +4,59 @@ package main
 
 import "sudoku/base"
 import "sudoku/text"
+import "context"
 import "flag"
 import "fmt"
 import "io/ioutil"
 import "os"
+import "path/filepath"
+import "runtime"
 import "strings"
+import "time"
 
 type PuzzleType struct {
-	Name string
+	Name   string
 	Parser func(text string) (*base.Puzzle, error)
+	// MultiParser, if not nil, is used instead of Parser to read input
+	// that can contain more than one puzzle.
+	MultiParser func(text string) ([]*base.Puzzle, error)
+	// Writer, if not nil, writes a puzzle in the format that Parser
+	// reads.
+	Writer  func(*base.Puzzle) (string, error)
 	Example string
 }
 
+// ParseAll returns all of the puzzles in text.
+func (pt *PuzzleType) ParseAll(input string) ([]*base.Puzzle, error) {
+	if pt.MultiParser != nil {
+		return pt.MultiParser(input)
+	}
+	puzzle, err := pt.Parser(input)
+	if _, ok := err.(text.UnsupportedConstraints); ok && puzzle != nil {
+		// Solve what we can.
+		return []*base.Puzzle{puzzle}, err
+	}
+	if err != nil {
+		return nil, err
+	}
+	return []*base.Puzzle{puzzle}, nil
+}
+
+func puzzle_type_names() []string {
+	names := []string{}
+	for _, pt := range PuzzleTypes {
+		names = append(names, pt.Name)
+	}
+	return names
+}
+
 func find_puzzle_type(name string) (*PuzzleType, error) {
-	supported := []string{}
 	for _, pt := range PuzzleTypes {
-		supported = append(supported, pt.Name)
 		if pt.Name == name {
 			return pt, nil
 		}
 	}
-	return nil, fmt.Errorf("The only supported values for the --puzzle flag are %s", strings.Join(supported, ", "))
+	return nil, fmt.Errorf("The only supported values for the --puzzle flag are %s", strings.Join(puzzle_type_names(), ", "))
 }
 
 func (pt *PuzzleType) String() string {
@@ -60,100 +92,442 @@ func fail(err error) {
 	os.Exit(-1)
 }
 
-func main() {
-	var input string
-	var output string
-	var puzzle_type PuzzleTypeVar
-
-	flag.StringVar(&input, "input", "", "Path to a file containing the unsolved puzzle.")
-	flag.StringVar(&output, "output", "-", "The file to write the solved puzzle to.")
-	flag.Var(&puzzle_type, "puzzle",
-		"The type of puzzle to solve, either 'sudoku' or 'kenken'.  If not specified an example puzzle is used.")
-	flag.Parse()
-
-	/*
-	fmt.Printf("flags.Visit:\n")
-	flag.VisitAll(func(f *flag.Flag) {
-		fmt.Printf("--%s=%v  %s\n", f.Name, f.Value, f.Usage)
-	})
-	fmt.Printf("Positional arguments: %v\n", flag.Args())
-	*/
-
-	if puzzle_type.Value == nil {
-		fmt.Printf("puzzle_type not set.  Available puzzle types: ")
-		for i, pt := range PuzzleTypes {
-			if i > 0 { fmt.Printf(", ") }
-			fmt.Printf("%s", pt.Name)
-		}
-		fmt.Printf("\n")
-		os.Exit(-1)
+// Command is a subcommand of the application.
+type Command struct {
+	Name string
+	// Description is a one line description of the command for help.
+	Description string
+	// Run parses the command's flags from args and does the command.
+	// It returns false if the command failed.
+	Run func(flags *flag.FlagSet, args []string) bool
+}
+
+var Commands []*Command
+
+func init() {
+	Commands = []*Command{
+		&Command{
+			Name:        "solve",
+			Description: "Solve puzzles and write the solutions and their justifications.",
+			Run:         solve_command,
+		},
+		&Command{
+			Name:        "check",
+			Description: "Report whether puzzles are valid and have a unique solution.",
+			Run:         check_command,
+		},
+		&Command{
+			Name:        "rate",
+			Description: "Report how difficult puzzles are.",
+			Run:         rate_command,
+		},
+		&Command{
+			Name:        "hint",
+			Description: "Show the next deduction that can be made about a puzzle.",
+			Run:         hint_command,
+		},
+		&Command{
+			Name:        "generate",
+			Description: "Make new puzzles.",
+			Run:         generate_command,
+		},
+		&Command{
+			Name:        "reduce",
+			Description: "Remove the givens and merge the cages that puzzles don't need.",
+			Run:         reduce_command,
+		},
+		&Command{
+			Name:        "sat",
+			Description: "Write puzzles as DIMACS CNF and check a SAT solver's solutions.",
+			Run:         sat_command,
+		},
+		&Command{
+			Name:        "convert",
+			Description: "Translate puzzles from one text format to another.",
+			Run:         convert_command,
+		},
+		&Command{
+			Name:        "play",
+			Description: "Play a puzzle in the terminal, with undo, checking and hints.",
+			Run:         play_command,
+		},
+		&Command{
+			Name:        "help",
+			Description: "Describe the commands, or the flags of the named command.",
+			Run:         help_command,
+		},
+	}
+}
+
+func find_command(name string) *Command {
+	for _, c := range Commands {
+		if c.Name == name {
+			return c
+		}
+	}
+	return nil
+}
+
+// command_flags returns the FlagSet for the command, whose usage
+// message describes the command and its flags.
+func command_flags(c *Command) *flag.FlagSet {
+	flags := flag.NewFlagSet(c.Name, flag.ExitOnError)
+	flags.Usage = func() {
+		out := flags.Output()
+		fmt.Fprintf(out, "Usage: %s %s [flags]\n\n%s\n\n",
+			filepath.Base(os.Args[0]), c.Name, c.Description)
+		flags.PrintDefaults()
+	}
+	return flags
+}
+
+func help_command(flags *flag.FlagSet, args []string) bool {
+	flags.Parse(args)
+	if flags.NArg() > 0 {
+		c := find_command(flags.Arg(0))
+		if c == nil {
+			fmt.Fprintf(os.Stderr, "Unknown command %s\n", flags.Arg(0))
+			return false
+		}
+		// Let the command's flags describe themselves.
+		c.Run(command_flags(c), []string{"-h"})
+		return true
+	}
+	fmt.Printf("Usage: %s <command> [flags]\n\nCommands:\n",
+		filepath.Base(os.Args[0]))
+	for _, c := range Commands {
+		fmt.Printf("  %-10s %s\n", c.Name, c.Description)
 	}
+	fmt.Printf("\nWithout a command, solve is assumed.  \"help <command>\" describes a command's flags.\n")
+	return true
+}
 
+// puzzle_flags are the flags that say where a command's puzzles come
+// from.
+type puzzle_flags struct {
+	puzzle_type PuzzleTypeVar
+	input       string
+}
+
+func add_puzzle_flags(flags *flag.FlagSet) *puzzle_flags {
+	pf := &puzzle_flags{}
+	flags.StringVar(&pf.input, "input", "", "Path to a file containing the puzzle.")
+	flags.Var(&pf.puzzle_type, "puzzle",
+		fmt.Sprintf("The type of puzzle, one of %s.  Without -input an example puzzle of this type is used.  Without -puzzle the type is chosen from the input, so one of the two is needed.",
+			strings.Join(puzzle_type_names(), ", ")))
+	return pf
+}
+
+// read returns the text of the input, or the example puzzle of the
+// -puzzle type if there is no input, and the puzzles it contains.
+// Without -puzzle the type is detected and reported.  Unsupported
+// constraints are only warned about.
+func (pf *puzzle_flags) read() (string, []*base.Puzzle, error) {
+	pt := pf.puzzle_type.Value
+	if pt == nil && pf.input == "" {
+		return "", nil, fmt.Errorf("Either -input or -puzzle, one of %s, is needed",
+			strings.Join(puzzle_type_names(), ", "))
+	}
 	var puzzle_string string
-	if input == "" {
-		puzzle_string = puzzle_type.Value.Example
+	if pf.input == "" {
+		puzzle_string = pt.Example
 	} else {
-		bytes, err := ioutil.ReadFile(input)
+		bytes, err := ioutil.ReadFile(pf.input)
 		if err != nil {
-			fail(fmt.Errorf("Can't read %s: %s", input, err))
+			return "", nil, fmt.Errorf("Can't read %s: %s", pf.input, err)
 		}
 		puzzle_string = string(bytes)
 	}
+	_, puzzles, err := parse_puzzles(pt, pf.input, puzzle_string, os.Stderr)
+	if err != nil && pt == nil && puzzles == nil {
+		err = fmt.Errorf("%s: %s", pf.input, err)
+	}
+	if _, ok := err.(text.UnsupportedConstraints); ok {
+		fmt.Fprintf(os.Stderr, "Warning: %s\n", err)
+		err = nil
+	}
+	return puzzle_string, puzzles, err
+}
 
-	puzzle, err := puzzle_type.Value.Parser(puzzle_string)
+// open_output opens the file named by an -output flag, where "-" is the
+// standard output, which the caller shouldn't close.
+func open_output(output string) (*os.File, error) {
+	if output == "-" {
+		return os.Stdout, nil
+	}
+	out, err := os.Create(output)
 	if err != nil {
-		border := strings.Repeat("=", 30)
-		fmt.Printf("%s\n%s\n%s\n",
-			border, puzzle_string, border)
-		fail(err)
+		return nil, fmt.Errorf("Can't open %s: %s", output, err)
 	}
+	return out, nil
+}
 
-	var out *os.File
-	if output == "-" {
-		out = os.Stdout
-	} else {
-		out, err := os.Create(output)
-		if err != nil {
-			fail(fmt.Errorf("Can't open %s: %s", output, err))
+// puzzle_name names one of several puzzles read from the same input.
+func puzzle_name(i int, puzzles []*base.Puzzle) string {
+	if len(puzzles) == 1 {
+		return "puzzle"
+	}
+	return fmt.Sprintf("puzzle %d of %d", i+1, len(puzzles))
+}
+
+func main() {
+	command := find_command("solve")
+	args := os.Args[1:]
+	if len(args) > 0 {
+		if c := find_command(args[0]); c != nil {
+			command = c
+			args = args[1:]
+		}
+	}
+	if !command.Run(command_flags(command), args) {
+		os.Exit(-1)
+	}
+}
+
+func solve_command(flags *flag.FlagSet, args []string) bool {
+	var output string
+	var verify bool
+	var batch bool
+	var workers int
+	var format string
+	var color bool
+	var seed int64
+	var choose string
+	var order string
+	var stats bool
+	var search search_options
+
+	pf := add_puzzle_flags(flags)
+	flags.StringVar(&output, "output", "-", "The file to write the solved puzzle to.")
+	flags.BoolVar(&verify, "verify", false,
+		"Solve the puzzles of the input file and of each file named on the command line and compare each result with the solution: section of its file.  Cells that don't match are reported.")
+	flags.BoolVar(&batch, "batch", false,
+		"Solve every puzzle of the input file and of the files, directories and glob patterns named on the command line and write a summary table.")
+	flags.IntVar(&workers, "workers", runtime.NumCPU(),
+		"The number of puzzles to solve at once in -batch mode.")
+	flags.BoolVar(&color, "color", false,
+		"Use ANSI colors to tell the givens of the solved puzzle from the values that were deduced.")
+	flags.StringVar(&format, "format", "text",
+		"How to write the solution: text, or json for a description of each puzzle's cells, metrics, justifications and errors that's meant to be read by programs.")
+	flags.Int64Var(&seed, "seed", 0,
+		"If not 0, guess the values of a cell in a random order seeded by this, which can find other solutions of a puzzle that has several.  The same seed always solves a puzzle the same way.")
+	flags.StringVar(&choose, "choose", "fewest",
+		fmt.Sprintf("How to choose the cell to guess: %s.", heuristic_names(base.CellChoosers)))
+	flags.StringVar(&order, "order", "",
+		fmt.Sprintf("The order to guess a cell's values in: %s.  smallest, or random with -seed, if not given.",
+			heuristic_names(base.ValueOrderers)))
+	flags.BoolVar(&stats, "stats", false,
+		"Write the number of guesses, backtracks and the deepest guess of each search.")
+	flags.DurationVar(&search.timeout, "timeout", 0,
+		"Give up on a puzzle whose solution isn't found in this long, for example 10s.  0 means never.")
+	flags.IntVar(&search.parallel, "parallel", 0,
+		"Split the search for each puzzle's solution among this many goroutines.  0 means one search without splitting.")
+	flags.Parse(args)
+
+	chooser := base.GetCellChooser(choose)
+	if chooser == nil {
+		fail(fmt.Errorf("-choose must be one of %s", heuristic_names(base.CellChoosers)))
+	}
+	var orderer base.ValueOrderer
+	if order != "" {
+		if orderer = base.GetValueOrderer(order); orderer == nil {
+			fail(fmt.Errorf("-order must be one of %s", heuristic_names(base.ValueOrderers)))
 		}
+	}
+
+	out, err := open_output(output)
+	if err != nil {
+		fail(err)
+	}
+	if output != "-" {
 		defer out.Close()
 	}
 
+	if format != "text" && format != "json" {
+		fail(fmt.Errorf("The only supported values for the -format flag are text and json"))
+	}
+
+	if verify {
+		files := flags.Args()
+		if pf.input != "" {
+			files = append([]string{pf.input}, files...)
+		}
+		if len(files) == 0 {
+			fail(fmt.Errorf("-verify needs the files to verify"))
+		}
+		return verify_files(out, pf.puzzle_type.Value, files)
+	}
+
+	if batch {
+		args := flags.Args()
+		if pf.input != "" {
+			args = append([]string{pf.input}, args...)
+		}
+		if len(args) == 0 {
+			fail(fmt.Errorf("-batch needs the files to solve"))
+		}
+		return batch_solve(out, pf.puzzle_type.Value, args, workers, search)
+	}
+
+	puzzle_string, puzzles, err := pf.read()
+	for _, puzzle := range puzzles {
+		puzzle.GuessSeed = seed
+		puzzle.CellChooser = chooser
+		puzzle.ValueOrderer = orderer
+	}
+	if format == "json" {
+		output := &json_output{Error: make_json_error(err)}
+		failed := err != nil
+		for _, puzzle := range puzzles {
+			jp := solve_json(puzzle, search)
+			failed = failed || jp.Error != nil
+			output.Puzzles = append(output.Puzzles, jp)
+		}
+		if err := write_json(out, output); err != nil {
+			fail(err)
+		}
+		return !failed
+	}
+	if err != nil {
+		border := strings.Repeat("=", 30)
+		fmt.Printf("%s\n%s\n%s\n",
+			border, puzzle_string, border)
+		fail(err)
+	}
+
 	// First write the original unsolved puzzle.
 	out.WriteString(puzzle_string)
 
+	failed := false
+	for i, puzzle := range puzzles {
+		if len(puzzles) > 1 {
+			fmt.Fprintf(out, "\nPuzzle %d of %d\n", i+1, len(puzzles))
+		}
+		if err := solve(out, puzzle, color, stats, search); err != nil {
+			fmt.Fprintf(os.Stderr, "Error while solving: %s\n", err.Error())
+			failed = true
+		}
+	}
+	return !failed
+}
+
+// solve solves the puzzle and writes its metadata, a drawing of the
+// solution and the justifications to out.
+func solve(out *os.File, puzzle *base.Puzzle, color bool, stats bool, search search_options) error {
 	pre_solve_value_count := puzzle.ValueCount()
 
+	for _, key := range puzzle.MetadataKeys() {
+		fmt.Fprintf(out, "%s: %s\n", key, puzzle.Metadata[key])
+	}
+
 	// Solve it
-	err = puzzle.GuessSolve()
+	puzzle, err := search.solve(puzzle)
 
 	// Write the answer
-	puzzle.Show(out)
+	options := []text.RenderOption{}
+	if color {
+		options = append(options, text.RenderColor)
+	}
+	out.WriteString("\n")
+	out.WriteString(text.Render(puzzle, options...))
+	out.WriteString("\n")
 
 	if !puzzle.IsSolved() {
-		fmt.Printf("Progress: %d %d %d %d\n\n",
+		fmt.Fprintf(out, "Progress: %d %d %d %d\n\n",
 			puzzle.MaxValueCount(),
 			pre_solve_value_count,
 			puzzle.ValueCount(),
 			puzzle.SolvedValueCount())
 	}
-	
+
+	if stats {
+		fmt.Fprintf(out, "Search: %d guesses, %d backtracks, depth %d\n\n",
+			puzzle.SearchStats.Guesses,
+			puzzle.SearchStats.Backtracks,
+			puzzle.SearchStats.MaxDepth)
+	}
+
 	// Write the justifications.
 	for _, j := range puzzle.Justifications {
 		out.WriteString(j.Pretty())
 		out.WriteString("\n")
 	}
 
-	if err != nil {
-		fail(fmt.Errorf("Error while solving: %s", err.Error()))
+	return err
+}
+
+// search_options are how the solve command searches for a puzzle's
+// solution.
+type search_options struct {
+	// timeout, if not 0, is how long to search before giving up.
+	timeout time.Duration
+	// parallel, if not 0, is the number of goroutines to split the
+	// search among.
+	parallel int
+}
+
+// solve solves the puzzle with GuessSolve or, if the search has a
+// timeout or is split, with SolveContext.  It returns the solved
+// puzzle, or, if there's no solution, the puzzle with what constraint
+// propagation deduces.
+func (search search_options) solve(puzzle *base.Puzzle) (*base.Puzzle, error) {
+	if search.timeout == 0 && search.parallel == 0 {
+		return puzzle, puzzle.GuessSolve()
+	}
+	ctx := context.Background()
+	if search.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, search.timeout)
+		defer cancel()
+	}
+	// Without -parallel a timeout shouldn't split the search among
+	// every CPU.
+	workers := search.parallel
+	if workers == 0 {
+		workers = 1
+	}
+	solutions, err := puzzle.SolveContext(ctx, base.SolveOptions{Workers: workers})
+	if err == context.DeadlineExceeded {
+		err = fmt.Errorf("no solution was found in %s", search.timeout)
+	}
+	if len(solutions) == 0 {
+		puzzle.DoConstraints()
+		return puzzle, err
+	}
+	solutions[0].SearchStats = puzzle.SearchStats
+	return solutions[0], err
+}
+
+// heuristic_names returns the names of the base.CellChoosers or
+// base.ValueOrderers.
+func heuristic_names(heuristics interface{}) string {
+	names := []string{}
+	switch h := heuristics.(type) {
+	case []base.CellChooser:
+		for _, c := range h {
+			names = append(names, c.Name())
+		}
+	case []base.ValueOrderer:
+		for _, o := range h {
+			names = append(names, o.Name())
+		}
 	}
+	return strings.Join(names, ", ")
+}
+
+func sudoku_writer(p *base.Puzzle) (string, error) {
+	return text.SudokuToText(p)
 }
 
+func kenken_writer(p *base.Puzzle) (string, error) {
+	return text.KenKenToText(p)
+}
 
-var PuzzleTypes = []*PuzzleType {
+var PuzzleTypes = []*PuzzleType{
 	&PuzzleType{
-		Name: "sudoku",
+		Name:   "sudoku",
 		Parser: text.TextToSudoku,
+		Writer: sudoku_writer,
 		Example: `
 	---7-----
 	1--------
@@ -166,8 +540,9 @@ var PuzzleTypes = []*PuzzleType {
 	-4----3--
 	`},
 	&PuzzleType{
-		Name: "kenken",
+		Name:   "kenken",
 		Parser: text.TextToKenKen,
+		Writer: kenken_writer,
 		Example: `
 	abccdd
 	abccee
@@ -189,4 +564,73 @@ var PuzzleTypes = []*PuzzleType {
 	k: 120 *
 	l:  15 *
 	`},
+	&PuzzleType{
+		Name:   "longkenken",
+		Parser: text.TextToLongKenKen,
+		Example: `
+	# Cage names can be longer than one letter.
+	a1  b1  c1  c1  d1  d1
+	a1  b1  c1  c1  e1  e1
+	a1  f1  f1  c1  g1  g1
+	5   f1  f1  h1  g1  1
+	i1  i1  j1  h1  k1  k1
+	i1  1   j1  l1  l1  k1
+
+	a1:  12 *
+	b1:  20 *
+	c1:  23 +
+	d1:   5 +
+	e1:  12 *
+	f1:  72 *
+	g1:  12 *
+	h1:   2 -
+	i1:  72 *
+	j1:   2 *
+	k1: 120 *
+	l1:  15 *   # Comments are allowed in the rules too.
+	`},
+	&PuzzleType{
+		Name:        "lines",
+		Parser:      text.LineToSudoku,
+		Writer:      text.SudokuToLine,
+		MultiParser: text.LinesToSudokus,
+		Example: `
+# Sudokus of 81 characters, one per line.
+...7.....1...........43.2..........6...5.9.........418....81.....2....5..4....3..
+2.....459....7..3.6.59...1.3..89...1..2...9..1...27..5.1...93.4.2..3....583.....2
+`},
+	&PuzzleType{
+		Name:   "sudokuart",
+		Parser: text.TolerantTextToSudoku,
+		Writer: sudoku_writer,
+		Example: `
+	+-------+-------+-------+
+	| 1 5 . | . . 9 | . 3 . |
+	| 3 7 . | . 6 . | . 2 4 |
+	| . . . | 5 . 3 | 7 . . |
+	+-------+-------+-------+
+	| 9 4 5 | . 8 . | 1 . . |
+	| . . . | . 1 2 | 4 . . |
+	| . 8 . | 4 . . | . 9 7 |
+	+-------+-------+-------+
+	| 8 . . | 2 . . | 6 7 . |
+	| 5 2 . | 6 . 1 | 9 . . |
+	| . . 6 | 8 . . | . . 3 |
+	+-------+-------+-------+
+	`},
+	&PuzzleType{
+		Name:    "solo",
+		Parser:  text.SoloToSudoku,
+		Writer:  text.SudokuToSolo,
+		Example: `3x3:c7e1k4_3a2j6c5a9i4_1_8d8_1e2d5b4d3b`},
+	&PuzzleType{
+		Name:    "keen",
+		Parser:  text.KeenToKenKen,
+		Writer:  text.KenKenToKeen,
+		Example: `6:__aa_a3_aa__a__a__ab_ba_aaca__a_5a,m12m20a23a5m12m72m12a5s2a1m72m2m120a1m15`},
+	&PuzzleType{
+		Name:   "fpuzzles",
+		Parser: text.FPuzzlesToPuzzle,
+		// "Binary Fusion" by Shye.
+		Example: `N4IgzglgXgpiBcBOANCALhNAbO8QCEIA7AQwCcBPAAgDEBXSAeyJFRLrQAtGyEQBlThTioydHGBho+AOR4BbElipg6AE0YBrOlTESqJAA6GsFAHSsQAczIQ1CANoPgAX2Sv3IAG5K6uAKyoVhBeMCzwaGIwbh6gPlh+CABMQSFhCJF+MTHevrgAbKmh4ZnR7tkAusjOOfGJSEXpEVHZ5W1xeQgALI0lLe25CbgAjL0Z/a5VzoP1KdZpfVnttZ3wgfPF40uxrbEzuADMY81ZUzuedQXHpbv7CEcbTTfLy2e7K0MIABzXEx/1o0eizK50m1VBt0uCBQQK2IP+uB6sJO8PKZzu8AewU2KPeLw6n3gc2xTz+Ayh8AA7L9TuCEQhASTgZDVjCmXC8XsKet2bi0XTyaskbznlzVoVkaL6fAfpKyZMKi4gA`},
 }
@@ -0,0 +1,320 @@
+package main
+
+import "sudoku/base"
+import "sudoku/text"
+import "bufio"
+import "flag"
+import "fmt"
+import "io"
+import "os"
+import "os/exec"
+import "strings"
+
+// play_move is an undoable change to one cell.
+type play_move struct {
+	key   base.GridKey
+	value int
+	marks base.ValueSet
+}
+
+// play_state is a game in progress.
+type play_state struct {
+	// puzzle has only the givens.
+	puzzle *base.Puzzle
+	// solved is the solved puzzle that entries are checked against.
+	solved  *base.Puzzle
+	values  map[base.GridKey]int
+	marks   map[base.GridKey]base.ValueSet
+	undo    []play_move
+	cursor  base.GridKey
+	pencil  bool
+	message string
+}
+
+func new_play_state(puzzle *base.Puzzle) (*play_state, error) {
+	solved := puzzle.Clone()
+	if err := solved.GuessSolve(); err != nil {
+		return nil, fmt.Errorf("The puzzle can't be solved: %s", err)
+	}
+	return &play_state{
+		puzzle: puzzle,
+		solved: solved,
+		values: make(map[base.GridKey]int),
+		marks:  make(map[base.GridKey]base.ValueSet),
+		cursor: base.MakeGridKey(1, 1),
+	}, nil
+}
+
+func (s *play_state) is_given(key base.GridKey) bool {
+	given, _ := s.puzzle.Grid[key].IsGiven()
+	return given
+}
+
+// set changes the value and pencil marks of the cursor's cell,
+// remembering how they were so that the change can be undone.
+func (s *play_state) set(value int, marks base.ValueSet) {
+	key := s.cursor
+	if s.is_given(key) {
+		s.message = "That cell is a given."
+		return
+	}
+	s.undo = append(s.undo, play_move{key: key, value: s.values[key], marks: s.marks[key]})
+	s.values[key] = value
+	s.marks[key] = marks
+	if s.is_solved() {
+		s.message = "Solved!"
+	}
+}
+
+func (s *play_state) undo_move() {
+	if len(s.undo) == 0 {
+		s.message = "There's nothing to undo."
+		return
+	}
+	m := s.undo[len(s.undo)-1]
+	s.undo = s.undo[:len(s.undo)-1]
+	s.values[m.key] = m.value
+	s.marks[m.key] = m.marks
+	s.cursor = m.key
+}
+
+// wrong returns the cells whose entered values don't match the solved
+// puzzle, in row major order.
+func (s *play_state) wrong() []base.GridKey {
+	keys := []base.GridKey{}
+	for y := 1; y <= s.puzzle.Size; y++ {
+		for x := 1; x <= s.puzzle.Size; x++ {
+			key := base.MakeGridKey(x, y)
+			if v := s.values[key]; v != 0 && !s.solved.Grid[key].HasPossibleValue(v) {
+				keys = append(keys, key)
+			}
+		}
+	}
+	return keys
+}
+
+func (s *play_state) is_solved() bool {
+	for key, c := range s.solved.Grid {
+		if _, v := c.IsSolved(); !s.is_given(key) && s.values[key] != v {
+			return false
+		}
+	}
+	return true
+}
+
+func (s *play_state) check() {
+	wrong := s.wrong()
+	if len(wrong) == 0 {
+		s.message = "Every value that's been entered is right."
+		return
+	}
+	cells := []string{}
+	for _, key := range wrong {
+		cells = append(cells, fmt.Sprintf("(%d, %d)", key.X, key.Y))
+	}
+	s.message = fmt.Sprintf("These cells are wrong: %s", strings.Join(cells, ", "))
+}
+
+// board returns a copy of the puzzle with the entered values as the
+// values of its cells, and the pencil marks of the other cells.
+func (s *play_state) board() (*base.Puzzle, map[*base.Cell]base.ValueSet) {
+	board := s.puzzle.Clone()
+	marks := make(map[*base.Cell]base.ValueSet)
+	for key, c := range board.Grid {
+		if s.is_given(key) {
+			continue
+		}
+		if v := s.values[key]; v != 0 {
+			c.Possibilities = base.NewValueSet([]int{v})
+		} else {
+			marks[c] = s.marks[key]
+		}
+	}
+	return board, marks
+}
+
+// hint uses the constraints to deduce the next value from the givens
+// and the entered values and explains it with its Justifications.
+func (s *play_state) hint() {
+	p := s.puzzle.Clone()
+	for key, v := range s.values {
+		if v == 0 {
+			continue
+		}
+		if _, err := p.Grid[key].MustBe(v, base.Pick, nil); err != nil {
+			s.message = fmt.Sprintf("The values that have been entered contradict each other: %s", err)
+			return
+		}
+	}
+	cell, justifications, err := p.Hint()
+	if err != nil {
+		s.message = fmt.Sprintf("The values that have been entered lead to a contradiction: %s", err)
+		return
+	}
+	if cell == nil {
+		s.message = "Nothing more can be deduced without guessing."
+		return
+	}
+	_, value := cell.IsSolved()
+	s.cursor = base.MakeGridKey(cell.X, cell.Y)
+	lines := []string{fmt.Sprintf("Cell(%d, %d) must be %d:", cell.X, cell.Y, value)}
+	for _, j := range justifications {
+		lines = append(lines, "  "+j.Pretty())
+	}
+	s.message = strings.Join(lines, "\n")
+}
+
+func (s *play_state) move(dx, dy int) {
+	x := (s.cursor.X-1+dx+s.puzzle.Size)%s.puzzle.Size + 1
+	y := (s.cursor.Y-1+dy+s.puzzle.Size)%s.puzzle.Size + 1
+	s.cursor = base.MakeGridKey(x, y)
+}
+
+// key does what the key says.  It returns false if the key quits.
+func (s *play_state) key(k rune) bool {
+	switch {
+	case k == 'q':
+		return false
+	case k == 'w' || k == 'A':
+		s.move(0, -1)
+	case k == 's' || k == 'B':
+		s.move(0, 1)
+	case k == 'd' || k == 'C':
+		s.move(1, 0)
+	case k == 'a' || k == 'D':
+		s.move(-1, 0)
+	case k >= '1' && k <= '9' && int(k-'0') <= s.puzzle.Size:
+		v := int(k - '0')
+		if s.pencil {
+			marks := s.marks[s.cursor]
+			s.set(0, marks.SetHasValue(v, !marks.HasValue(v)))
+		} else {
+			s.set(v, s.marks[s.cursor])
+		}
+	case k == '0' || k == '.' || k == ' ' || k == 0x7f || k == '\b':
+		s.set(0, 0)
+	case k == 'p':
+		s.pencil = !s.pencil
+	case k == 'u':
+		s.undo_move()
+	case k == 'c':
+		s.check()
+	case k == 'h':
+		s.hint()
+	}
+	return true
+}
+
+const play_help = "Keys: arrows or w a s d move, 1-9 enter a value, 0 or space clears, p switches pencil marks, u undoes, c checks, h hints, q quits."
+
+func (s *play_state) draw(out io.Writer, clear bool, options []text.RenderOption) {
+	if clear {
+		fmt.Fprint(out, "\x1b[H\x1b[2J")
+	}
+	board, marks := s.board()
+	fmt.Fprint(out, text.RenderBoard(board, board.Grid[s.cursor], marks, options...))
+	mode := "values"
+	if s.pencil {
+		mode = "pencil marks"
+	}
+	fmt.Fprintf(out, "Row %d, column %d.  Entering %s.\n%s\n",
+		s.cursor.Y, s.cursor.X, mode, play_help)
+	if s.message != "" {
+		fmt.Fprintf(out, "%s\n", s.message)
+	}
+}
+
+// is_terminal returns true if f is a terminal rather than a file or
+// pipe.
+func is_terminal(f *os.File) bool {
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// stty runs the stty command on the standard input and returns its
+// output.
+func stty(args ...string) (string, error) {
+	cmd := exec.Command("stty", args...)
+	cmd.Stdin = os.Stdin
+	out, err := cmd.Output()
+	return strings.TrimSpace(string(out)), err
+}
+
+func play_command(flags *flag.FlagSet, args []string) bool {
+	var color bool
+	pf := add_puzzle_flags(flags)
+	flags.BoolVar(&color, "color", true, "Use ANSI colors for the givens, the entered values and the cursor.")
+	flags.Parse(args)
+	_, puzzles, err := pf.read()
+	if err != nil {
+		fail(err)
+	}
+	if len(puzzles) != 1 {
+		fail(fmt.Errorf("play needs one puzzle, not %d", len(puzzles)))
+	}
+	s, err := new_play_state(puzzles[0])
+	if err != nil {
+		fail(err)
+	}
+	options := []text.RenderOption{}
+	if color {
+		options = append(options, text.RenderColor)
+	}
+
+	// On a terminal each key is read as it's typed.  Otherwise the
+	// keys are read from a script and the board is drawn after each
+	// line.
+	terminal := is_terminal(os.Stdin)
+	if terminal {
+		if saved, err := stty("-g"); err == nil {
+			if _, err := stty("cbreak", "-echo"); err == nil {
+				defer stty(saved)
+			}
+		}
+	}
+	return play_keys(s, bufio.NewReader(os.Stdin), os.Stdout, terminal, options)
+}
+
+// play_keys reads keys from in until they run out or one quits,
+// drawing the board to out.  On a terminal the board is redrawn after
+// each key; in a script it's drawn at the end of each line.  It
+// returns true if a key quit or the puzzle was solved.
+func play_keys(s *play_state, in *bufio.Reader, out io.Writer, terminal bool, options []text.RenderOption) bool {
+	quit := false
+	s.draw(out, terminal, options)
+	for {
+		k, _, err := in.ReadRune()
+		if err != nil {
+			break
+		}
+		if k == '\x1b' && in.Buffered() > 0 {
+			// An arrow key sends ESC [ and a letter all at once.
+			// An ESC on its own is just a key, so the next key
+			// isn't waited for.
+			if next, _ := in.Peek(1); len(next) > 0 && next[0] == '[' {
+				in.ReadRune()
+				k, _, _ = in.ReadRune()
+			}
+		}
+		if k == '\n' || k == '\r' {
+			if !terminal {
+				s.draw(out, false, options)
+				s.message = ""
+			}
+			continue
+		}
+		if terminal {
+			s.message = ""
+		}
+		if !s.key(k) {
+			quit = true
+			break
+		}
+		if terminal {
+			s.draw(out, true, options)
+		}
+	}
+	// Quitting isn't a failure, but a script that runs out of keys
+	// fails unless it solved the puzzle.
+	return quit || s.is_solved()
+}
@@ -0,0 +1,176 @@
+package main
+
+import "sudoku/base"
+import "sudoku/text"
+import "bufio"
+import "fmt"
+import "io/ioutil"
+import "reflect"
+import "strings"
+import "testing"
+
+func newTestPlay(t *testing.T) *play_state {
+	bytes, err := ioutil.ReadFile("examples/sudoku_1.txt")
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	p, err := text.TextToSudoku(string(bytes))
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	s, err := new_play_state(p)
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	return s
+}
+
+func TestPlayMove(t *testing.T) {
+	s := newTestPlay(t)
+	for _, test := range []struct {
+		key  rune
+		x, y int
+	}{
+		{'d', 2, 1},
+		{'a', 1, 1},
+		{'a', 9, 1},
+		{'C', 1, 1},
+		{'A', 1, 9},
+		{'s', 1, 1},
+		{'B', 1, 2},
+		{'w', 1, 1},
+		{'D', 9, 1},
+	} {
+		if !s.key(test.key) {
+			t.Fatalf("%c quit", test.key)
+		}
+		if s.cursor != base.MakeGridKey(test.x, test.y) {
+			t.Errorf("After %c the cursor is at %v, not (%d, %d)", test.key, s.cursor, test.x, test.y)
+		}
+	}
+	if s.key('q') {
+		t.Errorf("q didn't quit")
+	}
+}
+
+func TestPlaySetAndUndo(t *testing.T) {
+	s := newTestPlay(t)
+	s.key('5')
+	if s.message != "That cell is a given." || len(s.undo) != 0 {
+		t.Errorf("A given was changed: %q, %d moves", s.message, len(s.undo))
+	}
+	// Cell (2, 1) is 7.
+	key := base.MakeGridKey(2, 1)
+	s.key('d')
+	s.key('5')
+	if s.values[key] != 5 {
+		t.Errorf("Expected 5, got %d", s.values[key])
+	}
+	if wrong := s.wrong(); !reflect.DeepEqual(wrong, []base.GridKey{key}) {
+		t.Errorf("Expected (2, 1) to be wrong, got %v", wrong)
+	}
+	s.key('c')
+	if s.message != "These cells are wrong: (2, 1)" {
+		t.Errorf("Unexpected check: %q", s.message)
+	}
+	s.key('7')
+	if wrong := s.wrong(); len(wrong) != 0 {
+		t.Errorf("Expected nothing to be wrong, got %v", wrong)
+	}
+	s.key('s')
+	s.key('u')
+	if s.values[key] != 5 || s.cursor != key {
+		t.Errorf("Undo left %d at %v", s.values[key], s.cursor)
+	}
+	s.key('u')
+	s.key('u')
+	if s.values[key] != 0 || s.message != "There's nothing to undo." {
+		t.Errorf("Undoing everything left %d: %q", s.values[key], s.message)
+	}
+}
+
+func TestPlayPencil(t *testing.T) {
+	s := newTestPlay(t)
+	key := base.MakeGridKey(2, 1)
+	s.key('d')
+	s.key('p')
+	s.key('3')
+	s.key('4')
+	s.key('3')
+	if s.values[key] != 0 || s.marks[key] != base.NewValueSet([]int{4}) {
+		t.Errorf("Expected a pencil mark of 4, got %d %v", s.values[key], s.marks[key].Values())
+	}
+	s.key('p')
+	s.key('7')
+	if s.values[key] != 7 {
+		t.Errorf("Expected 7, got %d", s.values[key])
+	}
+	s.key('0')
+	if s.values[key] != 0 || s.marks[key] != 0 {
+		t.Errorf("Clearing left %d %v", s.values[key], s.marks[key].Values())
+	}
+}
+
+func TestPlaySolved(t *testing.T) {
+	s := newTestPlay(t)
+	for key, c := range s.solved.Grid {
+		if s.is_solved() {
+			t.Fatalf("Solved before (%d, %d) was entered", key.X, key.Y)
+		}
+		if s.is_given(key) {
+			continue
+		}
+		_, v := c.IsSolved()
+		s.cursor = key
+		s.set(v, 0)
+	}
+	if !s.is_solved() || s.message != "Solved!" {
+		t.Errorf("Not solved: %q", s.message)
+	}
+}
+
+func TestPlayHint(t *testing.T) {
+	s := newTestPlay(t)
+	s.key('h')
+	if !strings.Contains(s.message, "must be") {
+		t.Fatalf("Unexpected hint: %q", s.message)
+	}
+	// The hint moves the cursor to the cell, so entering its value
+	// there is right.
+	_, v := s.solved.Grid[s.cursor].IsSolved()
+	if !strings.HasPrefix(s.message, fmt.Sprintf("Cell(%d, %d) must be %d:", s.cursor.X, s.cursor.Y, v)) {
+		t.Errorf("The hint %q isn't for %v = %d", s.message, s.cursor, v)
+	}
+	s = newTestPlay(t)
+	// Row 1 already has a 2.
+	s.key('d')
+	s.key('2')
+	s.key('h')
+	if !strings.Contains(s.message, "contradict") {
+		t.Errorf("Expected a contradiction, got %q", s.message)
+	}
+}
+
+func TestPlayScript(t *testing.T) {
+	s := newTestPlay(t)
+	var out strings.Builder
+	in := bufio.NewReader(strings.NewReader("d5\nc\nu\nh\n"))
+	if play_keys(s, in, &out, false, nil) {
+		t.Errorf("The script shouldn't succeed without solving the puzzle")
+	}
+	for _, expected := range []string{
+		"These cells are wrong: (2, 1)",
+		"must be",
+	} {
+		if !strings.Contains(out.String(), expected) {
+			t.Errorf("Expected %q in:\n%s", expected, out.String())
+		}
+	}
+	if len(s.undo) != 0 {
+		t.Errorf("The move wasn't undone")
+	}
+	s = newTestPlay(t)
+	if !play_keys(s, bufio.NewReader(strings.NewReader("d\nq\n")), &out, false, nil) {
+		t.Errorf("Quitting should succeed")
+	}
+}
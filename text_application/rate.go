@@ -0,0 +1,34 @@
+package main
+
+import "flag"
+import "fmt"
+import "os"
+import "text/tabwriter"
+
+func rate_command(flags *flag.FlagSet, args []string) bool {
+	pf := add_puzzle_flags(flags)
+	flags.Parse(args)
+	_, puzzles, err := pf.read()
+	if err != nil {
+		fail(err)
+	}
+	ok := true
+	w := tabwriter.NewWriter(os.Stdout, 0, 8, 2, ' ', 0)
+	fmt.Fprintf(w, "PUZZLE\tDIFFICULTY\tPASSES\tGUESSES\n")
+	for i, puzzle := range puzzles {
+		name := puzzle_name(i, puzzles)
+		if title, found := puzzle.Metadata["title"]; found {
+			name = title
+		}
+		rating, err := puzzle.Rate()
+		if err != nil {
+			fmt.Fprintf(w, "%s\tunsolvable: %s\t\t\n", name, err)
+			ok = false
+			continue
+		}
+		fmt.Fprintf(w, "%s\t%s\t%d\t%d\n",
+			name, rating.Difficulty, rating.Passes, rating.Guesses)
+	}
+	w.Flush()
+	return ok
+}
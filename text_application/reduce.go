@@ -0,0 +1,77 @@
+package main
+
+import "sudoku/base"
+import "sudoku/generate"
+import "flag"
+import "fmt"
+import "os"
+import "strings"
+
+func reduce_command(flags *flag.FlagSet, args []string) bool {
+	var output string
+	var to PuzzleTypeVar
+	pf := add_puzzle_flags(flags)
+	flags.StringVar(&output, "output", "-", "The file to write the reduced puzzles to.")
+	flags.Var(&to, "to",
+		fmt.Sprintf("The type of puzzle text to write, one of %s.  kenken for KenKens and sudoku otherwise if not given.",
+			strings.Join(writer_names(), ", ")))
+	flags.Parse(args)
+	if to.Value != nil && to.Value.Writer == nil {
+		fail(fmt.Errorf("-to must be one of %s", strings.Join(writer_names(), ", ")))
+	}
+	_, puzzles, err := pf.read()
+	if err != nil {
+		fail(err)
+	}
+	out, err := open_output(output)
+	if err != nil {
+		fail(err)
+	}
+	if output != "-" {
+		defer out.Close()
+	}
+	ok := true
+	for i, puzzle := range puzzles {
+		name := puzzle_name(i, puzzles)
+		reduced, reduction, err := generate.Reduce(puzzle)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%s: %s\n", name, err)
+			ok = false
+			continue
+		}
+		for _, g := range reduction.Givens {
+			fmt.Fprintf(os.Stderr, "%s: the given %d at row %d, column %d is redundant\n",
+				name, g.Value, g.Y, g.X)
+		}
+		for _, m := range reduction.Merges {
+			fmt.Fprintf(os.Stderr, "%s: merged cages %s\n", name, m)
+		}
+		pt := to.Value
+		if pt == nil {
+			pt, _ = find_puzzle_type(default_writer(reduced))
+		}
+		written, err := pt.Writer(reduced)
+		if err != nil {
+			fail(fmt.Errorf("%s: %s", name, err))
+		}
+		if !strings.HasSuffix(written, "\n") {
+			written += "\n"
+		}
+		out.WriteString(written)
+	}
+	return ok
+}
+
+// default_writer returns the name of the puzzle type to write a
+// puzzle as when it isn't specified: kenken if it has cages and sudoku
+// otherwise.
+func default_writer(p *base.Puzzle) string {
+	for _, g := range p.Groups {
+		for _, c := range g.Constraints() {
+			if _, ok := c.(*base.KenKenCageConstraint); ok {
+				return "kenken"
+			}
+		}
+	}
+	return "sudoku"
+}
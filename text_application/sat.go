@@ -0,0 +1,136 @@
+package main
+
+import "sudoku/base"
+import "sudoku/sat"
+import "flag"
+import "fmt"
+import "io"
+import "io/ioutil"
+import "os"
+import "os/exec"
+import "strings"
+
+func sat_command(flags *flag.FlagSet, args []string) bool {
+	var cnf string
+	var model_file string
+	var solver string
+	pf := add_puzzle_flags(flags)
+	flags.StringVar(&cnf, "cnf", "",
+		"The file to write the puzzle to as a DIMACS CNF formula, - for standard output.")
+	flags.StringVar(&model_file, "model", "",
+		"A file with a SAT solver's model of the formula that -cnf wrote, to check against this solver's solution.")
+	flags.StringVar(&solver, "solver", "",
+		"The command of a SAT solver to run on the formula of each puzzle, with the name of the CNF file added.  Its model is checked against this solver's solution.")
+	flags.Parse(args)
+	if cnf == "" && model_file == "" && solver == "" {
+		fail(fmt.Errorf("One of -cnf, -model or -solver is needed"))
+	}
+	_, puzzles, err := pf.read()
+	if err != nil {
+		fail(err)
+	}
+	if (cnf != "" || model_file != "") && len(puzzles) > 1 {
+		fail(fmt.Errorf("-cnf and -model need a single puzzle, not %d", len(puzzles)))
+	}
+	ok := true
+	for i, puzzle := range puzzles {
+		name := puzzle_name(i, puzzles)
+		formula, err := sat.ToDIMACS(puzzle)
+		if err != nil {
+			fail(fmt.Errorf("%s: %s", name, err))
+		}
+		if cnf != "" {
+			out, err := open_output(cnf)
+			if err != nil {
+				fail(err)
+			}
+			out.WriteString(formula)
+			if cnf != "-" {
+				out.Close()
+			}
+		}
+		var model string
+		switch {
+		case model_file != "":
+			bytes, err := ioutil.ReadFile(model_file)
+			if err != nil {
+				fail(fmt.Errorf("Can't read %s: %s", model_file, err))
+			}
+			model = string(bytes)
+		case solver != "":
+			model, err = run_sat_solver(solver, formula)
+			if err != nil {
+				fail(fmt.Errorf("%s: %s", name, err))
+			}
+		default:
+			continue
+		}
+		if !check_sat_model(os.Stdout, name, puzzle, model) {
+			ok = false
+		}
+	}
+	return ok
+}
+
+// run_sat_solver runs the SAT solver command on a file of the formula
+// and returns what it writes, which should be its model.  SAT solvers
+// exit with 10 if the formula is satisfiable and 20 if it isn't.
+func run_sat_solver(solver string, formula string) (string, error) {
+	file, err := ioutil.TempFile("", "sudoku-*.cnf")
+	if err != nil {
+		return "", err
+	}
+	defer os.Remove(file.Name())
+	_, err = file.WriteString(formula)
+	file.Close()
+	if err != nil {
+		return "", err
+	}
+	command := strings.Fields(solver)
+	output, err := exec.Command(command[0], append(command[1:], file.Name())...).Output()
+	if exit, ok := err.(*exec.ExitError); ok && (exit.ExitCode() == 10 || exit.ExitCode() == 20) {
+		err = nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("%s: %s", solver, err)
+	}
+	return string(output), nil
+}
+
+// check_sat_model fills in a copy of the puzzle from a SAT solver's
+// model and reports whether that obeys the puzzle's constraints and
+// matches the solution that GuessSolve finds.  It returns false unless
+// it does.
+func check_sat_model(out io.Writer, name string, puzzle *base.Puzzle, model string) bool {
+	from_model := puzzle.Clone()
+	if err := sat.ReadModel(from_model, model); err != nil {
+		fmt.Fprintf(out, "%s: %s\n", name, err)
+		return false
+	}
+	if err := from_model.DoConstraints(); err != nil {
+		fmt.Fprintf(out, "%s: the SAT solver's solution breaks a constraint: %s\n", name, err)
+		return false
+	}
+	solved := puzzle.Clone()
+	if err := solved.GuessSolve(); err != nil {
+		fmt.Fprintf(out, "%s: the SAT solver found a solution but this solver didn't: %s\n", name, err)
+		return false
+	}
+	differences := []string{}
+	for _, c := range solved.Cells() {
+		got := from_model.Cell(c.X, c.Y).Possibilities
+		if got != c.Possibilities {
+			differences = append(differences, fmt.Sprintf("cell (%d, %d) is %s, not %s",
+				c.X, c.Y, got.String(","), c.Possibilities.String(",")))
+		}
+	}
+	if len(differences) > 0 {
+		fmt.Fprintf(out, "%s: the SAT solver's solution is different, so the puzzle has more than one:\n", name)
+		for _, d := range differences {
+			fmt.Fprintf(out, "  %s\n", d)
+		}
+		return false
+	}
+	fmt.Fprintf(out, "%s: the SAT solver's solution matches\n", name)
+	return true
+}
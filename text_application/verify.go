@@ -0,0 +1,70 @@
+package main
+
+import "sudoku/base"
+import "sudoku/text"
+import "fmt"
+import "io"
+import "io/ioutil"
+
+// verify_files solves the puzzles in each of the files and compares
+// them with the solution sections of the files.  If pt is nil the type
+// of each file's puzzles is detected.  It returns false if
+// any puzzle can't be read or doesn't match its solution.
+func verify_files(out io.Writer, pt *PuzzleType, files []string) bool {
+	ok := true
+	for _, file := range files {
+		bytes, err := ioutil.ReadFile(file)
+		if err != nil {
+			fmt.Fprintf(out, "%s: %s\n", file, err)
+			ok = false
+			continue
+		}
+		_, puzzles, err := parse_puzzles(pt, file, string(bytes), out)
+		if _, unsupported := err.(text.UnsupportedConstraints); unsupported {
+			fmt.Fprintf(out, "%s: warning: %s\n", file, err)
+			err = nil
+		}
+		if err != nil {
+			fmt.Fprintf(out, "%s: %s\n", file, err)
+			ok = false
+			continue
+		}
+		for i, puzzle := range puzzles {
+			name := file
+			if len(puzzles) > 1 {
+				name = fmt.Sprintf("%s, puzzle %d", file, i+1)
+			}
+			if !verify_puzzle(out, name, puzzle) {
+				ok = false
+			}
+		}
+	}
+	return ok
+}
+
+// verify_puzzle solves the puzzle and reports each Cell that doesn't
+// match its Solution.
+func verify_puzzle(out io.Writer, name string, puzzle *base.Puzzle) bool {
+	if puzzle.Solution == nil {
+		fmt.Fprintf(out, "%s: there's no solution to verify against\n", name)
+		return false
+	}
+	solve_err := puzzle.GuessSolve()
+	mismatches, err := puzzle.Verify()
+	if err != nil {
+		fmt.Fprintf(out, "%s: %s\n", name, err)
+		return false
+	}
+	if len(mismatches) == 0 {
+		fmt.Fprintf(out, "%s: verified\n", name)
+		return true
+	}
+	fmt.Fprintf(out, "%s: %d cells don't match the solution\n", name, len(mismatches))
+	if solve_err != nil {
+		fmt.Fprintf(out, "  Error while solving: %s\n", solve_err)
+	}
+	for _, m := range mismatches {
+		fmt.Fprintf(out, "  %s\n", m)
+	}
+	return false
+}